@@ -18,6 +18,7 @@ import (
 	"os"
 
 	"github.com/artilleryio/kubectl-artillery/commands"
+	"github.com/artilleryio/kubectl-artillery/internal/artillery"
 	"github.com/artilleryio/kubectl-artillery/internal/telemetry"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 )
@@ -28,9 +29,6 @@ const (
 
 	// Version controller version.
 	Version = "alpha"
-
-	// WorkerImage the Artillery image used by workers to run load tests.
-	WorkerImage = "artilleryio/artillery:latest"
 )
 
 // kubectl-artillery CLI entrypoint
@@ -38,7 +36,7 @@ func main() {
 	wd := "."
 	ioStreams := genericclioptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
 
-	tCfg := telemetry.NewConfig(AppName, Version, WorkerImage, nil)
+	tCfg := telemetry.NewConfig(AppName, Version, artillery.WorkerImage, nil)
 	tClient, err := telemetry.NewClient(tCfg)
 	if err != nil {
 		_, _ = ioStreams.ErrOut.Write([]byte(fmt.Sprintf("unable to create telemetry client: %s", err.Error())))
@@ -13,12 +13,15 @@
 package commands
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/artilleryio/kubectl-artillery/internal/artillery"
+	"github.com/artilleryio/kubectl-artillery/internal/registry"
 	"github.com/artilleryio/kubectl-artillery/internal/telemetry"
 	"github.com/posthog/posthog-go"
 	"github.com/spf13/cobra"
@@ -28,7 +31,16 @@ import (
 
 const generatetestExample = `- $ %[1]s generate <job-name> --script path/to/test-script
 - $ %[1]s generate <job-name> -s path/to/test-script
-- $ %[1]s generate <job-name> -s path/to/test-script [--namespace] [--out ] [--count ]`
+- $ %[1]s generate <job-name> -s path/to/test-script [--namespace] [--out ] [--count ]
+- $ %[1]s generate <job-name> -s path/to/test-script --config path/to/config
+- $ %[1]s generate <job-name> -s path/to/test-script -e functional --variables '{"userId": [1, 2]}'
+- $ %[1]s generate <job-name> -s path/to/test-script --count 4 --shard-payloads
+- $ %[1]s generate <job-name> -s path/to/test-script --count 5 --split-load
+- $ %[1]s generate <job-name> -s path/to/test-script --count 5 --start-barrier
+- $ %[1]s generate <job-name> -s path/to/test-script --requests cpu=1,memory=1Gi --toleration dedicated=load:NoSchedule --spread zone
+- $ %[1]s generate <job-name> -s path/to/test-script --image registry.example.com/artillery:2.0.0 --resolve-digest --image-pull-secret regcred
+- $ %[1]s generate <job-name> -s path/to/test-script --plugin-version slack=1.2.0
+- $ %[1]s generate <job-name> -s path/to/test-script --large-assets pvc`
 
 // newCmdGenerate creates the "generate" test command
 func newCmdGenerate(
@@ -49,9 +61,14 @@ func newCmdGenerate(
 			ns, _ := cmd.Flags().GetString("namespace")
 			outPath, _ := cmd.Flags().GetString("out")
 			count, _ := cmd.Flags().GetInt("count")
+			image, _ := cmd.Flags().GetString("image")
+
+			// report the image test workers actually run, resolved to its digest when requested
+			cfg := tCfg
+			cfg.WorkerImage = image
 
 			logger := artillery.NewIOLogger(io.Out, io.ErrOut)
-			telemetry.TelemeterGenerateManifests(args[0], testScriptPath, ns, outPath, count, tClient, tCfg, logger)
+			telemetry.TelemeterGenerateManifests(args[0], testScriptPath, ns, outPath, count, tClient, cfg, logger)
 			return nil
 		},
 	}
@@ -87,6 +104,151 @@ func newCmdGenerate(
 		"Optional. Specify how many test workers the created Job should run",
 	)
 
+	flags.String(
+		"config",
+		"",
+		"Optional. Specify path to an external artillery config file, bundled and passed to artillery run --config",
+	)
+
+	flags.StringP(
+		"environment",
+		"e",
+		"",
+		"Optional. Specify a test script environment to run, see artillery run --environment",
+	)
+
+	flags.String(
+		"target",
+		"",
+		"Optional. Specify a target URL overriding the test script target, see artillery run --target",
+	)
+
+	flags.String(
+		"overrides",
+		"",
+		"Optional. Specify a JSON object overriding parts of the test script, see artillery run --overrides",
+	)
+
+	flags.String(
+		"variables",
+		"",
+		"Optional. Specify a JSON object of variables available to scenarios, see artillery run --variables",
+	)
+
+	flags.Bool(
+		"shard-payloads",
+		false,
+		"Optional. Run an Indexed Job, splitting payload CSV files so each test worker reads its own rows",
+	)
+
+	flags.Bool(
+		"split-load",
+		false,
+		"Optional. Divide the test script phases across test workers, so the total load matches the test script",
+	)
+
+	flags.Bool(
+		"start-barrier",
+		false,
+		"Optional. Hold test workers until they are all running, so they start the first phase together",
+	)
+
+	flags.String(
+		"start-at",
+		"",
+		"Optional. Specify an RFC 3339 time test workers start together at, e.g. 2022-06-01T10:00:00Z. Implies --start-barrier",
+	)
+
+	flags.StringToString(
+		"requests",
+		nil,
+		"Optional. Specify test worker resource requests, e.g. cpu=500m,memory=512Mi",
+	)
+
+	flags.StringToString(
+		"limits",
+		nil,
+		"Optional. Specify test worker resource limits, e.g. cpu=1,memory=1Gi",
+	)
+
+	flags.StringToString(
+		"node-selector",
+		nil,
+		"Optional. Specify node labels test workers must be scheduled on, e.g. pool=load",
+	)
+
+	flags.StringArray(
+		"toleration",
+		nil,
+		"Optional. Specify a taint test workers tolerate as key[=value]:effect, e.g. dedicated=load:NoSchedule. Repeat for more",
+	)
+
+	flags.String(
+		"priority-class",
+		"",
+		"Optional. Specify the priority class of test worker Pods",
+	)
+
+	flags.String(
+		"affinity",
+		"",
+		"Optional. Specify node labels test workers must be scheduled on using label selector syntax, e.g. 'kubernetes.io/arch in (amd64,arm64)', or a YAML file holding a Pod affinity",
+	)
+
+	flags.String(
+		"spread",
+		"",
+		"Optional. Spread test workers evenly across nodes or zones, either node or zone",
+	)
+
+	flags.StringToString(
+		"plugin-version",
+		nil,
+		"Optional. Pin the version of a plugin or engine installed into test workers, e.g. slack=1.2.0",
+	)
+
+	flags.Bool(
+		"skip-plugin-install",
+		false,
+		"Optional. Do not install the plugins and engines required by the test script, when the image already has them",
+	)
+
+	flags.String(
+		"image",
+		artillery.WorkerImage,
+		"Optional. Specify the Artillery image test workers run",
+	)
+
+	flags.String(
+		"image-pull-policy",
+		"",
+		"Optional. Specify the image pull policy: Always, IfNotPresent or Never. Defaults to IfNotPresent for digests, Always otherwise",
+	)
+
+	flags.Bool(
+		"resolve-digest",
+		false,
+		"Optional. Pin the image to the digest its tag currently resolves to, for reproducible test runs",
+	)
+
+	flags.StringArray(
+		"image-pull-secret",
+		nil,
+		"Optional. Specify a Secret used to pull the image from a private registry. Repeat for more",
+	)
+
+	flags.String(
+		"large-assets",
+		string(artillery.AssetStrategyAuto),
+		"Optional. Specify how assets too large for a ConfigMap are delivered: auto, split, gzip or pvc",
+	)
+
+	flags.Bool(
+		"skip-validation",
+		false,
+		"Optional. Generate manifests without validating the test script",
+	)
+
 	if err := cmd.MarkFlagRequired("script"); err != nil {
 		return nil
 	}
@@ -110,6 +272,37 @@ func makeRunGenTest(workingDir string, io genericclioptions.IOStreams, cfg telem
 			return err
 		}
 
+		configPath, err := cmd.Flags().GetString("config")
+		if err != nil {
+			return err
+		}
+
+		if len(configPath) > 0 {
+			if err := validateTestScriptExists(configPath); err != nil {
+				return err
+			}
+		}
+
+		run, err := getRunOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err := run.Validate(); err != nil {
+			return err
+		}
+
+		skipValidation, err := cmd.Flags().GetBool("skip-validation")
+		if err != nil {
+			return err
+		}
+
+		if !skipValidation {
+			if err := validateTestScript(testScriptPath, configPath, run, io); err != nil {
+				return err
+			}
+		}
+
 		ns, err := cmd.Flags().GetString("namespace")
 		if err != nil {
 			return err
@@ -125,6 +318,88 @@ func makeRunGenTest(workingDir string, io genericclioptions.IOStreams, cfg telem
 			return err
 		}
 
+		largeAssets, err := cmd.Flags().GetString("large-assets")
+		if err != nil {
+			return err
+		}
+
+		strategy, err := artillery.ParseAssetStrategy(largeAssets)
+		if err != nil {
+			return err
+		}
+
+		shardPayloads, err := cmd.Flags().GetBool("shard-payloads")
+		if err != nil {
+			return err
+		}
+
+		splitLoad, err := cmd.Flags().GetBool("split-load")
+		if err != nil {
+			return err
+		}
+
+		if err := validateSplitLoad(splitLoad, count, run); err != nil {
+			return err
+		}
+
+		startBarrier, err := cmd.Flags().GetBool("start-barrier")
+		if err != nil {
+			return err
+		}
+
+		startAtValue, err := cmd.Flags().GetString("start-at")
+		if err != nil {
+			return err
+		}
+
+		var startAt time.Time
+		if len(startAtValue) > 0 {
+			startAt, err = time.Parse(time.RFC3339, startAtValue)
+			if err != nil {
+				return fmt.Errorf("start time %q must use RFC 3339, e.g. 2022-06-01T10:00:00Z", startAtValue)
+			}
+			startBarrier = true
+		}
+
+		scheduling, err := getWorkerScheduling(cmd)
+		if err != nil {
+			return err
+		}
+
+		image, err := getImage(cmd, io)
+		if err != nil {
+			return err
+		}
+
+		pullPolicyValue, err := cmd.Flags().GetString("image-pull-policy")
+		if err != nil {
+			return err
+		}
+
+		pullPolicy, err := artillery.ParsePullPolicy(pullPolicyValue)
+		if err != nil {
+			return err
+		}
+
+		pullSecrets, err := cmd.Flags().GetStringArray("image-pull-secret")
+		if err != nil {
+			return err
+		}
+
+		pluginVersions, err := cmd.Flags().GetStringToString("plugin-version")
+		if err != nil {
+			return err
+		}
+
+		skipPluginInstall, err := cmd.Flags().GetBool("skip-plugin-install")
+		if err != nil {
+			return err
+		}
+
+		if skipPluginInstall && len(pluginVersions) > 0 {
+			return errors.New("cannot pin plugin versions with --skip-plugin-install")
+		}
+
 		testName := args[0]
 		configMapName := fmt.Sprintf("%s-test-script", testName)
 
@@ -133,23 +408,113 @@ func makeRunGenTest(workingDir string, io genericclioptions.IOStreams, cfg telem
 			return err
 		}
 
-		if err := artillery.CopyFileTo(targetDir, testScriptPath); err != nil {
+		bundle, err := artillery.BundleTestScript(targetDir, testScriptPath, configPath)
+		if err != nil {
 			return err
 		}
 
-		job := artillery.NewTestJob(testName, ns, configMapName, filepath.Base(testScriptPath), count, cfg)
-		kustomization := artillery.NewKustomization(artillery.TestFilename, ns, configMapName, testScriptPath, artillery.LabelPrefix)
+		for _, asset := range bundle.Assets {
+			_, _ = io.Out.Write([]byte(fmt.Sprintf("%s bundled\n", filepath.Join(targetDir, asset))))
+		}
+
+		var packages []artillery.NpmPackage
+		if !skipPluginInstall {
+			packages, err = bundle.RequiredPackages(pluginVersions)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, p := range packages {
+			_, _ = io.Out.Write([]byte(fmt.Sprintf("%s installed into test workers by an init container\n", p)))
+		}
+
+		var workerScript string
+		if splitLoad {
+			plan, err := bundle.SplitLoad(count)
+			if err != nil {
+				return err
+			}
+			_, _ = io.Out.Write([]byte(fmt.Sprintf("%s\n", plan)))
+			workerScript = bundle.WorkerScript("$(WORKER_INDEX)")
+		}
 
-		msg, err := artillery.Generatables{
+		shardsConfigMapName := fmt.Sprintf("%s-payload-shards", testName)
+		if shardPayloads {
+			if err := bundle.ShardPayloads(count); err != nil {
+				return err
+			}
+			run = run.WithWorkerVariables()
+		}
+
+		for _, shards := range bundle.Shards {
+			_, _ = io.Out.Write([]byte(fmt.Sprintf("%s\n", shards)))
+		}
+
+		if err := bundle.StageLargeAssets(strategy, configMapName); err != nil {
+			return err
+		}
+
+		for _, large := range bundle.Large {
+			_, _ = io.Out.Write([]byte(fmt.Sprintf("%s\n", large.Describe(count))))
+		}
+
+		var claimName string
+		if bundle.UsesPVC() {
+			claimName = fmt.Sprintf("%s-assets", testName)
+		}
+
+		job := artillery.NewTestJob(testName, ns, configMapName, bundle.Script, count, cfg).
+			WithImage(image.String(), pullPolicy, pullSecrets).
+			WithPackages(packages).
+			WithConfigFile(bundle.Config).
+			WithRunOptions(run).
+			WithWorkerScripts(workerScript).
+			WithPayloadShards(bundle.ShardConfigMapNames(shardsConfigMapName), bundle.Shards).
+			WithLargeAssets(bundle.Large, claimName).
+			WithScheduling(scheduling)
+		kustomization := artillery.NewKustomization(artillery.TestFilename, ns, configMapName, bundle.Files(), artillery.LabelPrefix).
+			WithImage(image.Name, image.Tag, image.Digest)
+		for i, name := range bundle.ShardConfigMapNames(shardsConfigMapName) {
+			kustomization.WithConfigMap(name, bundle.ShardConfigMaps[i])
+		}
+		for _, large := range bundle.Large {
+			for _, part := range large.Parts {
+				kustomization.WithConfigMap(part.ConfigMap, []string{part.File})
+			}
+		}
+
+		generatables := artillery.Generatables{
 			{
 				Path:      filepath.Join(targetDir, artillery.TestFilename),
 				Marshaler: job,
 			},
-			{
-				Path:      filepath.Join(targetDir, "kustomization.yaml"),
-				Marshaler: kustomization,
-			},
-		}.Generate(2)
+		}
+
+		if startBarrier {
+			rbac := artillery.NewStartBarrierRBAC(testName, ns)
+			job.WithStartBarrier(rbac.Name()).WithStartAt(startAt)
+			kustomization.WithResource(artillery.StartBarrierFilename)
+			generatables = append(generatables, artillery.Generatable{
+				Path:      filepath.Join(targetDir, artillery.StartBarrierFilename),
+				Marshaler: rbac,
+			})
+		}
+
+		if len(claimName) > 0 {
+			kustomization.WithResource(artillery.AssetsClaimFilename)
+			generatables = append(generatables, artillery.Generatable{
+				Path:      filepath.Join(targetDir, artillery.AssetsClaimFilename),
+				Marshaler: artillery.NewAssetsPersistentVolumeClaim(claimName, ns, bundle.Large, count),
+			})
+		}
+
+		generatables = append(generatables, artillery.Generatable{
+			Path:      filepath.Join(targetDir, "kustomization.yaml"),
+			Marshaler: kustomization,
+		})
+
+		msg, err := generatables.Generate(2)
 		if err != nil {
 			return err
 		}
@@ -195,3 +560,177 @@ func validateTestScriptExists(s string) error {
 
 	return nil
 }
+
+// validateSplitLoad validates the load can be split across test workers.
+func validateSplitLoad(splitLoad bool, count int, run artillery.RunOptions) error {
+	if !splitLoad {
+		return nil
+	}
+	if count < 2 {
+		return errors.New("--split-load needs a --count of at least 2 workers")
+	}
+	if strings.Contains(run.Overrides, `"phases"`) {
+		return errors.New("--split-load cannot split phases set by --overrides, move them to the test script")
+	}
+	return nil
+}
+
+// getImage gets the image test workers run, resolving its tag to a digest when requested.
+// The image flag is updated with the image used, to be reported by telemetry.
+func getImage(cmd *cobra.Command, io genericclioptions.IOStreams) (registry.Reference, error) {
+	value, err := cmd.Flags().GetString("image")
+	if err != nil {
+		return registry.Reference{}, err
+	}
+
+	image, err := registry.ParseReference(value)
+	if err != nil {
+		return image, err
+	}
+
+	resolve, err := cmd.Flags().GetBool("resolve-digest")
+	if err != nil {
+		return image, err
+	}
+
+	if resolve && len(image.Digest) == 0 {
+		ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
+		defer cancel()
+
+		tagged := image.String()
+		image, err = registry.NewResolver().Resolve(ctx, image)
+		if err != nil {
+			return image, err
+		}
+		_, _ = io.Out.Write([]byte(fmt.Sprintf("%s resolved to %s\n", tagged, image)))
+	}
+
+	return image, cmd.Flags().Set("image", image.String())
+}
+
+// getWorkerScheduling gets the resources and scheduling constraints of test workers,
+// from the CLI settings file overridden by flags.
+func getWorkerScheduling(cmd *cobra.Command) (artillery.WorkerScheduling, error) {
+	settings, err := artillery.GetOrCreateCLISettings()
+	if err != nil {
+		return artillery.WorkerScheduling{}, err
+	}
+
+	scheduling, err := settings.GetWorkerScheduling()
+	if err != nil {
+		return scheduling, err
+	}
+
+	var flagged artillery.WorkerScheduling
+
+	requests, err := cmd.Flags().GetStringToString("requests")
+	if err != nil {
+		return scheduling, err
+	}
+
+	flagged.Resources.Requests, err = artillery.ParseResourceList(requests)
+	if err != nil {
+		return scheduling, err
+	}
+
+	limits, err := cmd.Flags().GetStringToString("limits")
+	if err != nil {
+		return scheduling, err
+	}
+
+	flagged.Resources.Limits, err = artillery.ParseResourceList(limits)
+	if err != nil {
+		return scheduling, err
+	}
+
+	flagged.NodeSelector, err = cmd.Flags().GetStringToString("node-selector")
+	if err != nil {
+		return scheduling, err
+	}
+
+	tolerations, err := cmd.Flags().GetStringArray("toleration")
+	if err != nil {
+		return scheduling, err
+	}
+
+	for _, t := range tolerations {
+		toleration, err := artillery.ParseToleration(t)
+		if err != nil {
+			return scheduling, err
+		}
+		flagged.Tolerations = append(flagged.Tolerations, toleration)
+	}
+
+	flagged.PriorityClassName, err = cmd.Flags().GetString("priority-class")
+	if err != nil {
+		return scheduling, err
+	}
+
+	affinity, err := cmd.Flags().GetString("affinity")
+	if err != nil {
+		return scheduling, err
+	}
+
+	if len(affinity) > 0 {
+		flagged.Affinity, err = artillery.ParseAffinity(affinity)
+		if err != nil {
+			return scheduling, err
+		}
+	}
+
+	flagged.Spread, err = cmd.Flags().GetString("spread")
+	if err != nil {
+		return scheduling, err
+	}
+
+	if err := flagged.Validate(); err != nil {
+		return scheduling, err
+	}
+
+	return scheduling.Override(flagged), nil
+}
+
+// getRunOptions gets the artillery run options passed to test workers from flags.
+func getRunOptions(cmd *cobra.Command) (artillery.RunOptions, error) {
+	var (
+		run artillery.RunOptions
+		err error
+	)
+
+	run.Environment, err = cmd.Flags().GetString("environment")
+	if err != nil {
+		return run, err
+	}
+
+	run.Target, err = cmd.Flags().GetString("target")
+	if err != nil {
+		return run, err
+	}
+
+	run.Overrides, err = cmd.Flags().GetString("overrides")
+	if err != nil {
+		return run, err
+	}
+
+	run.Variables, err = cmd.Flags().GetString("variables")
+	if err != nil {
+		return run, err
+	}
+
+	return run, nil
+}
+
+// validateTestScript validates the test script, run with an optional external config file and artillery run options,
+// against the Artillery test script schema. Warnings are reported without failing.
+func validateTestScript(s, configPath string, run artillery.RunOptions, io genericclioptions.IOStreams) error {
+	err := artillery.ValidateTestScriptRun(s, configPath, run)
+	var invalid artillery.ValidationErrors
+	if errors.As(err, &invalid) {
+		if invalid.Failed() {
+			return fmt.Errorf("invalid test script, fix it or use --skip-validation:\n%s", invalid.Error())
+		}
+		_, _ = io.Out.Write([]byte(fmt.Sprintf("%s\n", invalid.Error())))
+		return nil
+	}
+	return err
+}
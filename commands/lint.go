@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/artilleryio/kubectl-artillery/internal/artillery"
+	"github.com/artilleryio/kubectl-artillery/internal/kube"
+	"github.com/artilleryio/kubectl-artillery/internal/telemetry"
+	"github.com/posthog/posthog-go"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+const lintExample = `- $ %[1]s lint path/to/test-script
+- $ %[1]s lint path/to/test-script [--namespace ] [--max-worker-rate ]
+- $ %[1]s lint path/to/test-script --fix`
+
+// newCmdLint creates the test script lint command
+func newCmdLint(
+	io genericclioptions.IOStreams,
+	cliName string,
+	tClient posthog.Client,
+	tCfg telemetry.Config,
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "lint [OPTIONS]",
+		Short:   "Checks a test script against the K8s Services it targets",
+		Example: fmt.Sprintf(lintExample, cliName),
+		RunE:    makeRunLint(io),
+		PostRunE: func(cmd *cobra.Command, args []string) error {
+			ns, _ := cmd.Flags().GetString("namespace")
+			fix, _ := cmd.Flags().GetBool("fix")
+
+			logger := artillery.NewIOLogger(io.Out, io.ErrOut)
+			telemetry.TelemeterLint(args[0], ns, fix, tClient, tCfg, logger)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.SortFlags = false
+
+	flags.StringP(
+		"namespace",
+		"n",
+		"",
+		"Optional. Specify the namespace of the services the test script targets, defaults to the current namespace",
+	)
+
+	flags.Int(
+		"max-worker-rate",
+		artillery.DefaultMaxWorkerArrivalRate,
+		"Optional. Specify the arrival rate a single test worker realistically sustains",
+	)
+
+	flags.Bool(
+		"fix",
+		false,
+		"Optional. Fix mechanical issues in place, e.g. target ports the Service does not expose",
+	)
+
+	return cmd
+}
+
+// makeRunLint creates the RunE function used to lint a test script
+func makeRunLint(io genericclioptions.IOStreams) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validateLint(args); err != nil {
+			return err
+		}
+		testScriptPath := args[0]
+
+		if err := validateTestScriptExists(testScriptPath); err != nil {
+			return err
+		}
+
+		ns, err := cmd.Flags().GetString("namespace")
+		if err != nil {
+			return err
+		}
+
+		maxWorkerRate, err := cmd.Flags().GetInt("max-worker-rate")
+		if err != nil {
+			return err
+		}
+
+		fix, err := cmd.Flags().GetBool("fix")
+		if err != nil {
+			return err
+		}
+
+		ctl, err := kube.NewClient(genericclioptions.NewConfigFlags(true))
+		if err != nil {
+			return err
+		}
+
+		if len(ns) == 0 {
+			ns = ctl.CfgNamespace
+		}
+
+		issues, err := artillery.NewLinter(ns, maxWorkerRate, ctl).Lint(context.TODO(), testScriptPath)
+		if err != nil {
+			return err
+		}
+
+		remaining := len(issues)
+		if fix {
+			fixed, err := artillery.FixTestScript(testScriptPath, issues)
+			if err != nil {
+				return err
+			}
+
+			if fixed > 0 {
+				_, _ = io.Out.Write([]byte(fmt.Sprintf("%s: %d issues fixed\n", testScriptPath, fixed)))
+				issues, err = artillery.NewLinter(ns, maxWorkerRate, ctl).Lint(context.TODO(), testScriptPath)
+				if err != nil {
+					return err
+				}
+				remaining = len(issues)
+			}
+		}
+
+		for _, issue := range issues {
+			_, _ = io.Out.Write([]byte(issue.String() + "\n"))
+		}
+
+		if remaining > 0 {
+			return fmt.Errorf("%d issues found", remaining)
+		}
+
+		_, _ = io.Out.Write([]byte(fmt.Sprintf("%s: no issues found\n", testScriptPath)))
+		return nil
+	}
+}
+
+// validateLint validates lint RunE arguments.
+func validateLint(args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing test script path")
+	}
+	if len(args) > 1 {
+		return errors.New("unknown arguments detected")
+	}
+	return nil
+}
@@ -17,18 +17,33 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/artilleryio/kubectl-artillery/internal/artillery"
 	"github.com/artilleryio/kubectl-artillery/internal/kube"
 	"github.com/artilleryio/kubectl-artillery/internal/telemetry"
 	"github.com/posthog/posthog-go"
 	"github.com/spf13/cobra"
+	k8sValidation "k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 )
 
 const scaffoldExample = `- $ %[1]s scaffold <k8s-Service-name> 
 - $ %[1]s scaffold <k8s-service1> <k8s-service2>
-- $ %[1]s scaffold <k8s-Service-name> [--namespace ] [--out ]`
+- $ %[1]s scaffold <k8s-Service-name> [--namespace ] [--out ]
+- $ %[1]s scaffold <k8s-Service-name> --update [--prune] [--dry-run]
+- $ %[1]s scaffold <k8s-headless-Service-name> --per-pod
+- $ %[1]s scaffold <k8s-Service-name> --unmapped-probes pods|service
+- $ %[1]s scaffold <k8s-ExternalName-Service-name> --external-name [--external-paths /health,/status]
+- $ %[1]s scaffold ksvc/<knative-Service-name> [--warm-up ]
+- $ %[1]s scaffold helm-release/<helm-release-name>
+- $ %[1]s scaffold kafkatopic/<strimzi-KafkaTopic-name> [--kafka-rate ] [--kafka-duration ] [--kafka-payload ]
+- $ %[1]s scaffold <k8s-Service-name> --autoscale-test [--rate-per-replica ]
+- $ %[1]s scaffold [<k8s-Service-name>] -f path/to/manifests/
+- $ %[1]s scaffold [<k8s-Service-name>] -k path/to/kustomization/
+- $ kubectl get svc,deploy -o yaml | %[1]s scaffold -f -
+- $ %[1]s scaffold <k8s-service1> <k8s-service2> --single-file --name <test-name> [--weight <k8s-service1>=3]
+- $ %[1]s scaffold --graphql-schema schema.json --service <k8s-Service-name> [--graphql-path /graphql]`
 
 // newCmdScaffold creates the test script scaffold command
 func newCmdScaffold(
@@ -40,7 +55,7 @@ func newCmdScaffold(
 ) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "scaffold [OPTIONS]",
-		Short:   "Scaffolds test scripts from K8s services using liveness probe HTTP endpoints",
+		Short:   "Scaffolds test scripts from K8s services using liveness probe HTTP endpoints and annotations",
 		Example: fmt.Sprintf(scaffoldExample, cliName),
 		RunE:    makeRunScaffold(workingDir, io),
 		PostRunE: func(cmd *cobra.Command, args []string) error {
@@ -70,13 +85,176 @@ func newCmdScaffold(
 		"Optional. Specify output path to write the test script files",
 	)
 
+	flags.StringSliceP(
+		"filename",
+		"f",
+		nil,
+		"Optional. Scaffold from local manifest files or directories without cluster access, use - to read from stdin",
+	)
+
+	flags.StringP(
+		"kustomize",
+		"k",
+		"",
+		"Optional. Scaffold from a kustomization directory rendered locally without cluster access",
+	)
+
+	flags.Bool(
+		"single-file",
+		false,
+		"Optional. Merge all test scripts into a single test script with a named scenario per service, requires --name",
+	)
+
+	flags.String(
+		"name",
+		"",
+		"Optional. Specify the name of the single test script, used with --single-file",
+	)
+
+	flags.StringToInt(
+		"weight",
+		nil,
+		"Optional. Specify scenario weights by service name, used with --single-file, e.g. --weight orders=3,payments=1",
+	)
+
+	flags.Bool(
+		"update",
+		false,
+		"Optional. Update existing test scripts with new endpoints instead of overwriting them, keeping hand-edited flows, phases and plugins",
+	)
+
+	flags.Bool(
+		"prune",
+		false,
+		"Optional. Drop scaffolded requests for endpoints that no longer exist instead of marking them with a comment, used with --update",
+	)
+
+	flags.Bool(
+		"dry-run",
+		false,
+		"Optional. Show the changes to existing test scripts without writing any files, used with --update",
+	)
+
+	flags.Bool(
+		"per-pod",
+		false,
+		"Optional. Target every ready Pod behind a headless Service using per-Pod DNS names, e.g. StatefulSet replicas",
+	)
+
+	flags.String(
+		"unmapped-probes",
+		"",
+		"Optional. Test liveness probes on ports the Service does not map, targeting Pods directly (pods), or a generated companion headless Service (service)",
+	)
+
+	flags.Bool(
+		"external-name",
+		false,
+		"Optional. Scaffold ExternalName Services against their spec.externalName",
+	)
+
+	flags.StringSlice(
+		"external-paths",
+		nil,
+		fmt.Sprintf("Optional. Specify paths to test for ExternalName Services, overrides the %s annotation. Defaults to /", kube.ExternalPathsAnnotation),
+	)
+
+	flags.Int(
+		"warm-up",
+		30,
+		"Optional. Specify the warm-up phase duration in seconds for Knative Services, absorbing scale-from-zero. Use 0 to disable it",
+	)
+
+	flags.String(
+		"graphql-schema",
+		"",
+		"Optional. Scaffold GraphQL queries for every root Query field from an introspection result JSON file, requires --service",
+	)
+
+	flags.String(
+		"service",
+		"",
+		"Optional. Specify the K8s Service exposing the GraphQL endpoint, used with --graphql-schema",
+	)
+
+	flags.String(
+		"graphql-path",
+		"/graphql",
+		"Optional. Specify the GraphQL endpoint path, used with --graphql-schema",
+	)
+
+	flags.Int(
+		"kafka-rate",
+		10,
+		"Optional. Specify the rate of messages per second published to Kafka topics",
+	)
+
+	flags.Int(
+		"kafka-duration",
+		60,
+		"Optional. Specify the duration in seconds of publishing to Kafka topics",
+	)
+
+	flags.String(
+		"kafka-payload",
+		`{"id": "{{ $randomString() }}"}`,
+		"Optional. Specify the message payload template published to Kafka topics",
+	)
+
+	flags.Bool(
+		"autoscale-test",
+		false,
+		"Optional. Add an autoscale environment with load phases derived from the HorizontalPodAutoscaler of a Service's workload",
+	)
+
+	flags.Int(
+		"rate-per-replica",
+		10,
+		"Optional. Specify the arrival rate a single replica handles at its scaling target, used with --autoscale-test",
+	)
+
 	return cmd
 }
 
 // makeRunScaffold creates the RunE function used to scaffold a test script
 func makeRunScaffold(workingDir string, io genericclioptions.IOStreams) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		if err := validateScaffold(args); err != nil {
+		filenames, err := cmd.Flags().GetStringSlice("filename")
+		if err != nil {
+			return err
+		}
+
+		kustomizeDir, err := cmd.Flags().GetString("kustomize")
+		if err != nil {
+			return err
+		}
+
+		graphQLSchemaPath, err := cmd.Flags().GetString("graphql-schema")
+		if err != nil {
+			return err
+		}
+
+		graphQLService, err := cmd.Flags().GetString("service")
+		if err != nil {
+			return err
+		}
+
+		graphQLPath, err := cmd.Flags().GetString("graphql-path")
+		if err != nil {
+			return err
+		}
+
+		if err := validateGraphQL(graphQLSchemaPath, graphQLService); err != nil {
+			return err
+		}
+
+		offline := len(filenames) > 0 || len(kustomizeDir) > 0
+		if err := validateScaffold(args, offline || len(graphQLSchemaPath) > 0); err != nil {
+			return err
+		}
+
+		targets, err := parseScaffoldTargets(args)
+		if err != nil {
 			return err
 		}
 
@@ -90,66 +268,648 @@ func makeRunScaffold(workingDir string, io genericclioptions.IOStreams) func(cmd
 			return err
 		}
 
-		targetDir, err := artillery.MkdirAllTargetOrDefault(workingDir, outPath, artillery.DefaultScriptsDir)
+		singleFile, err := cmd.Flags().GetBool("single-file")
+		if err != nil {
+			return err
+		}
+
+		singleFileName, err := cmd.Flags().GetString("name")
+		if err != nil {
+			return err
+		}
+
+		weights, err := cmd.Flags().GetStringToInt("weight")
+		if err != nil {
+			return err
+		}
+
+		if err := validateSingleFile(singleFile, singleFileName, weights); err != nil {
+			return err
+		}
+
+		update, err := cmd.Flags().GetBool("update")
+		if err != nil {
+			return err
+		}
+
+		prune, err := cmd.Flags().GetBool("prune")
+		if err != nil {
+			return err
+		}
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return err
+		}
+
+		if err := validateUpdate(update, prune, dryRun); err != nil {
+			return err
+		}
+
+		perPod, err := cmd.Flags().GetBool("per-pod")
+		if err != nil {
+			return err
+		}
+
+		unmappedProbes, err := cmd.Flags().GetString("unmapped-probes")
+		if err != nil {
+			return err
+		}
+
+		if err := validateUnmappedProbes(unmappedProbes); err != nil {
+			return err
+		}
+
+		externalName, err := cmd.Flags().GetBool("external-name")
 		if err != nil {
 			return err
 		}
 
-		ctl, err := kube.NewClient(genericclioptions.NewConfigFlags(true))
+		externalPaths, err := cmd.Flags().GetStringSlice("external-paths")
 		if err != nil {
 			return err
 		}
 
-		if len(ns) == 0 {
-			ns = ctl.CfgNamespace
+		warmUp, err := cmd.Flags().GetInt("warm-up")
+		if err != nil {
+			return err
 		}
 
-		queryResults, err := kube.DoQuery(context.TODO(), args, ns, ctl)
+		kafka, err := kafkaOptionsFromFlags(cmd)
 		if err != nil {
 			return err
 		}
 
+		autoscaleTest, err := cmd.Flags().GetBool("autoscale-test")
+		if err != nil {
+			return err
+		}
+
+		ratePerReplica, err := cmd.Flags().GetInt("rate-per-replica")
+		if err != nil {
+			return err
+		}
+
+		targetDir, err := artillery.MkdirAllTargetOrDefault(workingDir, outPath, artillery.DefaultScriptsDir)
+		if err != nil {
+			return err
+		}
+
+		var ctl *kube.Client
+		var scripts []artillery.NamedTestScript
+		var manifestFiles artillery.Generatables
+		var queryServices func(svcNames []string) (kube.QueryResults, error)
+		if offline {
+			if err := validateOffline(targets, autoscaleTest); err != nil {
+				return err
+			}
+
+			manifests := &kube.Manifests{}
+			if err := manifests.ReadFiles(filenames, io.In); err != nil {
+				return err
+			}
+
+			if len(kustomizeDir) > 0 {
+				if err := manifests.ReadKustomization(kustomizeDir); err != nil {
+					return err
+				}
+			}
+
+			queryServices = func(svcNames []string) (kube.QueryResults, error) {
+				return manifests.DoQuery(svcNames), nil
+			}
+		} else {
+			ctl, err = kube.NewClient(genericclioptions.NewConfigFlags(true))
+			if err != nil {
+				return err
+			}
+
+			if len(ns) == 0 {
+				ns = ctl.CfgNamespace
+			}
+
+			scripts, err = scaffoldKnativeServices(targets.knativeServices, ns, warmUp, ctl, io)
+			if err != nil {
+				return err
+			}
+
+			kafkaScripts, err := scaffoldKafkaTopics(targets.kafkaTopics, ns, kafka, ctl, io)
+			if err != nil {
+				return err
+			}
+			scripts = append(scripts, kafkaScripts...)
+
+			svcNames, err := helmReleaseServices(targets.helmReleases, ns, ctl, io)
+			if err != nil {
+				return err
+			}
+			targets.services = append(targets.services, svcNames...)
+
+			queryServices = func(svcNames []string) (kube.QueryResults, error) {
+				return kube.DoQuery(context.TODO(), svcNames, ns, ctl)
+			}
+		}
+
+		if len(graphQLSchemaPath) > 0 {
+			script, err := scaffoldGraphQL(graphQLSchemaPath, graphQLService, graphQLPath, queryServices)
+			if err != nil {
+				return err
+			}
+			scripts = append(scripts, script)
+		}
+
+		queryResults, err := queryServices(targets.services)
+		if err != nil {
+			return err
+		}
+
+		if !offline {
+			for _, qr := range queryResults.NoReadyEndpoints() {
+				svc := qr.SelectionServiceName()
+				_, _ = io.Out.Write([]byte(fmt.Sprintf("services \"%s\" has no ready endpoints, scaffolded tests will fail until Pods are ready\n", svc)))
+			}
+		}
+
 		for _, qr := range queryResults.QueryMisses() {
 			_, _ = io.Out.Write([]byte(fmt.Sprintf("services \"%s\" not found\n", qr.QueriedServiceName())))
 		}
 
-		if !queryResults.HasQueryHits() {
-			return nil
+		for _, qr := range queryResults.EndpointMisses() {
+			svc := qr.SelectionServiceName()
+			if !qr.HasUnmappedProbes() {
+				_, _ = io.Out.Write([]byte(fmt.Sprintf("services \"%s\" has no liveness probe or %s annotation endpoints, or ports mapping to endpoints\n", svc, kube.EndpointsAnnotation)))
+			} else if len(unmappedProbes) == 0 {
+				_, _ = io.Out.Write([]byte(fmt.Sprintf("services \"%s\" has no ports mapping to liveness probe endpoints, use --unmapped-probes to test them\n", svc)))
+			}
 		}
 
-		for _, qr := range queryResults.LivenessMisses() {
-			svc := qr.SelectionServiceName()
-			_, _ = io.Out.Write([]byte(fmt.Sprintf("services \"%s\" has no liveness probe endpoints, or ports mapping to endpoints\n", svc)))
+		hits := queryResults.EndpointHits()
+		if len(unmappedProbes) > 0 {
+			hits = append(hits, queryResults.UnmappedProbeHits()...)
+		}
+
+		for _, result := range hits {
+			probes := result.ServiceProbes()
+			if perPod {
+				probes = podServiceProbesOrDefault(result, io)
+			}
+
+			switch unmappedProbes {
+			case "pods":
+				unmapped := result.UnmappedPodProbes()
+				if result.HasUnmappedProbes() && len(unmapped) == 0 {
+					_, _ = io.Out.Write([]byte(fmt.Sprintf("services \"%s\" has no ready Pods to target unmapped liveness probes\n", result.SelectionServiceName())))
+				}
+				probes = append(probes, unmapped...)
+			case "service":
+				if companion := result.CompanionService(); companion != nil {
+					probes = append(probes, result.CompanionServiceProbes()...)
+					manifestFiles = append(manifestFiles, artillery.Generatable{
+						Path:      filepath.Join(targetDir, fmt.Sprintf("service_%s.yaml", companion.Name)),
+						Marshaler: &artillery.Service{Service: companion},
+					})
+				}
+			}
+
+			annotated, err := result.AnnotatedEndpoints()
+			if err != nil {
+				_, _ = io.Out.Write([]byte(fmt.Sprintf("services \"%s\" %s\n", result.SelectionServiceName(), err.Error())))
+			}
+
+			for _, port := range result.StreamingPorts() {
+				svc := result.SelectionServiceName()
+				scripts = append(scripts, artillery.NamedTestScript{
+					Name:   fmt.Sprintf("%s_%s", svc, port.Name),
+					Script: artillery.NewStreamingTestScript(svc, port),
+				})
+			}
+
+			if len(probes) == 0 && annotated == nil {
+				continue
+			}
+
+			ts := artillery.NewTestScript(probes).WithAnnotatedEndpoints(annotated)
+			if perPod && result.IsHeadless() {
+				// flows target Pods, the test script targets the Service rather than an arbitrary Pod
+				ts.WithTarget(result.ServiceProbes())
+			}
+			if autoscaleTest {
+				autoscaling, err := kube.GetAutoscaling(context.TODO(), result, ns, ctl)
+				if err != nil {
+					return err
+				}
+
+				if autoscaling == nil {
+					_, _ = io.Out.Write([]byte(fmt.Sprintf("services \"%s\" has no HorizontalPodAutoscaler for its workload\n", result.SelectionServiceName())))
+				} else {
+					ts.WithAutoscaleEnvironment(autoscaling, ratePerReplica)
+				}
+			}
+
+			scripts = append(scripts, artillery.NamedTestScript{
+				Name:   result.SelectionServiceName(),
+				Script: ts,
+			})
+		}
+
+		for _, result := range queryResults.ExternalNameHits() {
+			svc := result.SelectionServiceName()
+			if !externalName {
+				_, _ = io.Out.Write([]byte(fmt.Sprintf("services \"%s\" is an ExternalName service, use --external-name to scaffold it\n", svc)))
+				continue
+			}
+
+			probes := result.ExternalNameProbes(externalPaths)
+			if len(probes) == 0 {
+				_, _ = io.Out.Write([]byte(fmt.Sprintf("services \"%s\" is an ExternalName service exposing no HTTP ports, skipped\n", svc)))
+				continue
+			}
+
+			scripts = append(scripts, artillery.NamedTestScript{
+				Name:   svc,
+				Script: artillery.NewTestScript(probes),
+			})
 		}
 
-		if !queryResults.HasLivenessHits() {
+		if len(scripts) == 0 && len(manifestFiles) == 0 {
 			return nil
 		}
 
-		var scripts artillery.Generatables
-		for _, result := range queryResults.LivenessHits() {
-			ts := artillery.NewTestScript(result.ServiceProbes())
-			scripts = append(scripts, artillery.Generatable{
-				Path:      filepath.Join(targetDir, fmt.Sprintf("test-script_%s.yaml", result.SelectionServiceName())),
-				Marshaler: ts,
+		var files artillery.Generatables
+		if singleFile {
+			for name := range weights {
+				if !artillery.HasNamedTestScript(scripts, name) {
+					_, _ = io.Out.Write([]byte(fmt.Sprintf("weight for \"%s\" ignored, no test script scaffolded for it\n", name)))
+				}
+			}
+
+			merged, err := artillery.MergeTestScripts(scripts, weights)
+			if err != nil {
+				return err
+			}
+
+			files = append(files, artillery.Generatable{
+				Path:      filepath.Join(targetDir, fmt.Sprintf("test-script_%s.yaml", singleFileName)),
+				Marshaler: merged,
 			})
+		} else {
+			for _, script := range scripts {
+				files = append(files, artillery.Generatable{
+					Path:      filepath.Join(targetDir, fmt.Sprintf("test-script_%s.yaml", script.Name)),
+					Marshaler: script.Script,
+				})
+			}
+		}
+
+		if update {
+			files, err = updateTestScripts(files, prune, io)
+			if err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			for _, f := range append(files, manifestFiles...) {
+				if _, ok := f.Marshaler.(*artillery.TestScriptUpdate); !ok {
+					_, _ = io.Out.Write([]byte(fmt.Sprintf("%s would be generated\n", f.Path)))
+				}
+			}
+			return nil
 		}
-		msg, err := scripts.Generate(2)
+
+		msg, err := append(files, manifestFiles...).Generate(2)
 		if err != nil {
 			return err
 		}
 
-		_, _ = io.Out.Write([]byte(msg + "\n"))
+		if msg != "" {
+			_, _ = io.Out.Write([]byte(msg + "\n"))
+		}
 
 		return nil
 	}
 }
 
-// validateScaffold validates scaffold command arguments
-func validateScaffold(args []string) error {
-	if len(args) == 0 {
+// updateTestScripts replaces test scripts that already exist with updates of the existing files,
+// printing a diff of each update. Test scripts updated without changes are not written again.
+func updateTestScripts(files artillery.Generatables, prune bool, io genericclioptions.IOStreams) (artillery.Generatables, error) {
+	var out artillery.Generatables
+	for _, f := range files {
+		ts, ok := f.Marshaler.(*artillery.TestScript)
+		if !ok || !artillery.DirOrFileExists(f.Path) {
+			out = append(out, f)
+			continue
+		}
+
+		u, err := artillery.NewTestScriptUpdate(f.Path, ts, prune, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		if !u.HasChanges() {
+			_, _ = io.Out.Write([]byte(fmt.Sprintf("%s unchanged\n", f.Path)))
+			continue
+		}
+
+		_, _ = io.Out.Write([]byte(u.Diff()))
+		_, _ = io.Out.Write([]byte(fmt.Sprintf("%s: %d endpoints added, %d removed\n", f.Path, u.Added, u.Removed)))
+		out = append(out, artillery.Generatable{Path: f.Path, Marshaler: u})
+	}
+	return out, nil
+}
+
+// scaffoldKnativeServices returns test scripts for Knative Services, targeting their status url.
+func scaffoldKnativeServices(
+	names []string,
+	ns string,
+	warmUp int,
+	ctl *kube.Client,
+	io genericclioptions.IOStreams,
+) ([]artillery.NamedTestScript, error) {
+	var scripts []artillery.NamedTestScript
+	for _, name := range names {
+		ksvc, err := kube.GetKnativeService(context.TODO(), name, ns, ctl)
+		if err != nil {
+			return nil, err
+		}
+
+		if ksvc == nil {
+			_, _ = io.Out.Write([]byte(fmt.Sprintf("ksvc \"%s\" not found\n", name)))
+			continue
+		}
+
+		if !ksvc.IsReady() {
+			_, _ = io.Out.Write([]byte(fmt.Sprintf("ksvc \"%s\" has no status url, is it ready?\n", name)))
+			continue
+		}
+
+		scripts = append(scripts, artillery.NamedTestScript{
+			Name:   ksvc.Name,
+			Script: artillery.NewTestScript(ksvc.ServiceProbes()).WithWarmUp(warmUp, 1),
+		})
+	}
+	return scripts, nil
+}
+
+// scaffoldGraphQL returns a test script POSTing representative queries for every root Query field
+// of a GraphQL schema, targeting a K8s Service's GraphQL endpoint.
+func scaffoldGraphQL(
+	schemaPath, svcName, graphQLPath string,
+	queryServices func(svcNames []string) (kube.QueryResults, error),
+) (artillery.NamedTestScript, error) {
+	schema, err := artillery.ReadGraphQLSchema(schemaPath)
+	if err != nil {
+		return artillery.NamedTestScript{}, err
+	}
+
+	results, err := queryServices([]string{svcName})
+	if err != nil {
+		return artillery.NamedTestScript{}, err
+	}
+
+	if len(results.QueryMisses()) > 0 {
+		return artillery.NamedTestScript{}, fmt.Errorf("services \"%s\" not found", svcName)
+	}
+
+	endpoint := results[0].ServiceUrl()
+	if endpoint == nil {
+		return artillery.NamedTestScript{}, fmt.Errorf("services \"%s\" has no ports", svcName)
+	}
+	endpoint.Path = graphQLPath
+
+	ts, err := artillery.NewTestScript(nil).WithGraphQLQueries(schema, endpoint)
+	if err != nil {
+		return artillery.NamedTestScript{}, err
+	}
+
+	return artillery.NamedTestScript{
+		Name:   fmt.Sprintf("%s_graphql", svcName),
+		Script: ts,
+	}, nil
+}
+
+// kafkaOptions defines how scaffolded test scripts publish to Kafka topics.
+type kafkaOptions struct {
+	rate     int
+	duration int
+	payload  string
+}
+
+// kafkaOptionsFromFlags returns Kafka scaffold options set using command flags.
+func kafkaOptionsFromFlags(cmd *cobra.Command) (kafkaOptions, error) {
+	var out kafkaOptions
+	var err error
+
+	if out.rate, err = cmd.Flags().GetInt("kafka-rate"); err != nil {
+		return out, err
+	}
+
+	if out.duration, err = cmd.Flags().GetInt("kafka-duration"); err != nil {
+		return out, err
+	}
+
+	if out.payload, err = cmd.Flags().GetString("kafka-payload"); err != nil {
+		return out, err
+	}
+
+	if out.rate <= 0 || out.duration <= 0 {
+		return out, errors.New("--kafka-rate and --kafka-duration must be greater than 0")
+	}
+
+	return out, nil
+}
+
+// scaffoldKafkaTopics returns test scripts publishing to Strimzi KafkaTopics using the kafka engine.
+func scaffoldKafkaTopics(
+	names []string,
+	ns string,
+	kafka kafkaOptions,
+	ctl *kube.Client,
+	io genericclioptions.IOStreams,
+) ([]artillery.NamedTestScript, error) {
+	var scripts []artillery.NamedTestScript
+	for _, name := range names {
+		topic, err := kube.GetKafkaTopic(context.TODO(), name, ns, ctl)
+		if err != nil {
+			return nil, err
+		}
+
+		if topic == nil {
+			_, _ = io.Out.Write([]byte(fmt.Sprintf("kafkatopic \"%s\" not found\n", name)))
+			continue
+		}
+
+		scripts = append(scripts, artillery.NamedTestScript{
+			Name:   fmt.Sprintf("kafka_%s", topic.Name),
+			Script: artillery.NewKafkaTestScript(topic, kafka.rate, kafka.duration, kafka.payload),
+		})
+	}
+	return scripts, nil
+}
+
+// helmReleaseServices returns the names of all Services installed by Helm releases.
+func helmReleaseServices(releases []string, ns string, ctl *kube.Client, io genericclioptions.IOStreams) ([]string, error) {
+	var out []string
+	for _, release := range releases {
+		manifests, err := kube.GetHelmReleaseManifests(context.TODO(), release, ns, ctl)
+		if err != nil {
+			return nil, err
+		}
+
+		if manifests == nil {
+			_, _ = io.Out.Write([]byte(fmt.Sprintf("helm-release \"%s\" not found\n", release)))
+			continue
+		}
+
+		svcNames := manifests.ServiceNames()
+		if len(svcNames) == 0 {
+			_, _ = io.Out.Write([]byte(fmt.Sprintf("helm-release \"%s\" has no services\n", release)))
+		}
+		out = append(out, svcNames...)
+	}
+	return out, nil
+}
+
+// podServiceProbesOrDefault returns probes targeting every ready Pod of a headless Service.
+// Defaults to the Service's probes when per-Pod targets are not available.
+func podServiceProbesOrDefault(result kube.QueryResult, io genericclioptions.IOStreams) kube.ServiceProbes {
+	svc := result.SelectionServiceName()
+	if !result.IsHeadless() {
+		_, _ = io.Out.Write([]byte(fmt.Sprintf("services \"%s\" is not headless, cannot target Pods individually\n", svc)))
+		return result.ServiceProbes()
+	}
+
+	probes := result.PodServiceProbes()
+	if len(probes) == 0 {
+		return result.ServiceProbes()
+	}
+
+	return probes
+}
+
+// scaffoldTargets groups scaffold arguments by the kind of resource they reference.
+type scaffoldTargets struct {
+	services        []string
+	knativeServices []string
+	helmReleases    []string
+	kafkaTopics     []string
+}
+
+// parseScaffoldTargets parses scaffold arguments into scaffold targets.
+// Arguments are either K8s Service names, or <kind>/<name> resource references.
+// e.g. nginx, svc/nginx, ksvc/hello, helm-release/shop or kafkatopic/orders
+func parseScaffoldTargets(args []string) (scaffoldTargets, error) {
+	var out scaffoldTargets
+	for _, arg := range args {
+		kind, name := "svc", arg
+		if i := strings.Index(arg, "/"); i >= 0 {
+			kind, name = strings.ToLower(arg[:i]), arg[i+1:]
+		}
+
+		if len(name) == 0 {
+			return out, fmt.Errorf("missing resource name in %q", arg)
+		}
+
+		switch kind {
+		case "svc", "service", "services":
+			out.services = append(out.services, name)
+		case "ksvc", "kservice":
+			out.knativeServices = append(out.knativeServices, name)
+		case "helm-release", "helmrelease":
+			out.helmReleases = append(out.helmReleases, name)
+		case "kafkatopic", "kafkatopics", "kt":
+			out.kafkaTopics = append(out.kafkaTopics, name)
+		default:
+			return out, fmt.Errorf("cannot scaffold %q, unsupported resource kind %q", arg, kind)
+		}
+	}
+
+	return out, nil
+}
+
+// validateScaffold validates scaffold command arguments.
+// Scaffolding from local manifests does not require service names.
+func validateScaffold(args []string, offline bool) error {
+	if len(args) == 0 && !offline {
 		return errors.New("missing service name or names")
 	}
 
 	return nil
 }
+
+// validateGraphQL validates GraphQL scaffold options.
+func validateGraphQL(schemaPath, svcName string) error {
+	if len(schemaPath) > 0 && len(svcName) == 0 {
+		return errors.New("--graphql-schema requires a --service exposing the GraphQL endpoint")
+	}
+
+	if len(schemaPath) == 0 && len(svcName) > 0 {
+		return errors.New("--service can only be used with --graphql-schema")
+	}
+
+	return nil
+}
+
+// validateSingleFile validates options used to merge all test scripts into a single test script.
+func validateSingleFile(singleFile bool, name string, weights map[string]int) error {
+	if !singleFile {
+		if len(name) > 0 || len(weights) > 0 {
+			return errors.New("--name and --weight can only be used with --single-file")
+		}
+		return nil
+	}
+
+	if len(name) == 0 {
+		return errors.New("--single-file requires a test script --name")
+	}
+
+	if invalids := k8sValidation.NameIsDNSSubdomain(name, false); len(invalids) > 0 {
+		return fmt.Errorf("test script name %s must be a valid DNS subdomain name, \n%s", name, strings.Join(invalids, "\n- "))
+	}
+
+	for svc, weight := range weights {
+		if weight < 1 {
+			return fmt.Errorf("weight for %q must be at least 1", svc)
+		}
+	}
+
+	return nil
+}
+
+// validateUpdate validates --prune and --dry-run are only used when updating test scripts.
+func validateUpdate(update, prune, dryRun bool) error {
+	if !update && (prune || dryRun) {
+		return errors.New("--prune and --dry-run can only be used with --update")
+	}
+	return nil
+}
+
+// validateUnmappedProbes validates how liveness probes on ports a Service does not map are tested.
+func validateUnmappedProbes(mode string) error {
+	switch mode {
+	case "", "pods", "service":
+		return nil
+	}
+	return fmt.Errorf("unsupported --unmapped-probes %q, use pods or service", mode)
+}
+
+// validateOffline validates scaffold targets and options can be scaffolded without cluster access.
+func validateOffline(targets scaffoldTargets, autoscaleTest bool) error {
+	if len(targets.knativeServices) > 0 {
+		return errors.New("ksvc targets require cluster access, cannot be used with --filename or --kustomize")
+	}
+
+	if len(targets.helmReleases) > 0 {
+		return errors.New("helm-release targets require cluster access, cannot be used with --filename or --kustomize")
+	}
+
+	if len(targets.kafkaTopics) > 0 {
+		return errors.New("kafkatopic targets require cluster access, cannot be used with --filename or --kustomize")
+	}
+
+	if autoscaleTest {
+		return errors.New("--autoscale-test requires cluster access, cannot be used with --filename or --kustomize")
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Bundle the files of a test, written next to each other to be mounted under /data by the test Job.
+// Test script and config file paths referencing assets are rewritten to the assets' bundled file names.
+type Bundle struct {
+	Dir    string
+	Script string
+	// Workers the test scripts of each test worker when the load is split, see: SplitLoad.
+	Workers []string
+	Config  string
+	Assets  []string
+	Large   []LargeAsset
+	Shards  []PayloadShards
+	// ShardConfigMaps the shard files of each payload shards ConfigMap, see: ShardPayloads.
+	ShardConfigMaps [][]string
+	// sources maps bundled file names to their source paths, used to detect conflicting file names.
+	sources map[string]string
+	// payloads maps bundled payload CSV file names to whether their first line is a header.
+	payloads map[string]bool
+}
+
+// Files returns the bundled file names, used as the test's ConfigMap keys.
+// Large assets and sharded payloads are excluded, they are generated into their own ConfigMaps.
+func (b *Bundle) Files() []string {
+	files := append([]string{b.Script}, b.Workers...)
+	if len(b.Config) > 0 {
+		files = append(files, b.Config)
+	}
+	for _, a := range b.Assets {
+		if !b.IsLarge(a) && !b.IsSharded(a) {
+			files = append(files, a)
+		}
+	}
+	return files
+}
+
+// BundleTestScript bundles a test script, an optional external config file,
+// and every asset they reference into a directory.
+// Assets are processors, payload CSVs and includeFiles, found in config and environments.
+// Asset paths are relative to the file referencing them, as Artillery resolves them.
+func BundleTestScript(dir, scriptPath, configPath string) (*Bundle, error) {
+	b := &Bundle{Dir: dir, sources: map[string]string{}, payloads: map[string]bool{}}
+
+	var err error
+	b.Script, err = b.addYAML(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(configPath) > 0 {
+		b.Config, err = b.addYAML(configPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// addYAML bundles a test script or config file, rewriting and bundling the assets it references.
+func (b *Bundle) addYAML(path string) (string, error) {
+	name, err := b.claim(path)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+
+	config := mappingValue(&doc, "config")
+	payloads := payloadReferences(config)
+
+	var edits []lineEdit
+	for _, ref := range assetReferences(config) {
+		if strings.Contains(ref.Value, "{{") {
+			continue
+		}
+
+		src := ref.Value
+		if !filepath.IsAbs(src) {
+			src = filepath.Join(filepath.Dir(path), src)
+		}
+		if !DirOrFileExists(src) {
+			return "", fmt.Errorf("%s:%d:%d: cannot find referenced file %s", path, ref.Line, ref.Column, ref.Value)
+		}
+
+		asset, err := b.claim(src)
+		if err != nil {
+			return "", err
+		}
+		if err := CopyFileTo(b.Dir, src); err != nil {
+			return "", err
+		}
+		if !b.hasAsset(asset) {
+			b.Assets = append(b.Assets, asset)
+		}
+		if skipHeader, isPayload := payloads[ref]; isPayload {
+			b.payloads[asset] = b.payloads[asset] || skipHeader
+		}
+
+		if ref.Value != asset {
+			edits = append(edits, lineEdit{line: ref.Line, old: ref.Value, new: asset})
+		}
+	}
+
+	bundled, _ := applyLineEdits(data, edits)
+	return name, os.WriteFile(filepath.Join(b.Dir, name), bundled, 0644)
+}
+
+// claim returns a bundled file name for a source path,
+// failing when another source path already uses the same file name.
+func (b *Bundle) claim(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	name := filepath.Base(abs)
+	if existing, found := b.sources[name]; found && existing != abs {
+		return "", fmt.Errorf("cannot bundle both %s and %s as %s, rename one of them", existing, abs, name)
+	}
+	b.sources[name] = abs
+	return name, nil
+}
+
+// hasAsset checks whether an asset is already bundled.
+func (b *Bundle) hasAsset(name string) bool {
+	for _, a := range b.Assets {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// assetReferences returns the scalar nodes referencing asset files in a config and its environments.
+func assetReferences(config *yaml3.Node) []*yaml3.Node {
+	if config == nil {
+		return nil
+	}
+
+	refs := configAssetReferences(config)
+	environments := mappingValue(config, "environments")
+	if environments != nil && environments.Kind == yaml3.MappingNode {
+		for i := 1; i < len(environments.Content); i += 2 {
+			refs = append(refs, configAssetReferences(environments.Content[i])...)
+		}
+	}
+	return refs
+}
+
+// configAssetReferences returns the processor, payload and includeFiles scalar nodes of a config or environment.
+func configAssetReferences(config *yaml3.Node) []*yaml3.Node {
+	var refs []*yaml3.Node
+	if processor := mappingValue(config, "processor"); nonEmptyScalar(processor) {
+		refs = append(refs, processor)
+	}
+
+	for _, payload := range configPayloads(config) {
+		if p := mappingValue(payload, "path"); nonEmptyScalar(p) {
+			refs = append(refs, p)
+		}
+	}
+
+	if includes := mappingValue(config, "includeFiles"); includes != nil {
+		for _, include := range includes.Content {
+			if nonEmptyScalar(include) {
+				refs = append(refs, include)
+			}
+		}
+	}
+	return refs
+}
+
+// payloadReferences returns the path scalar nodes of payloads in a config and its environments,
+// mapped to whether the payload's first line is a header.
+func payloadReferences(config *yaml3.Node) map[*yaml3.Node]bool {
+	refs := map[*yaml3.Node]bool{}
+	if config == nil {
+		return refs
+	}
+
+	configs := []*yaml3.Node{config}
+	environments := mappingValue(config, "environments")
+	if environments != nil && environments.Kind == yaml3.MappingNode {
+		for i := 1; i < len(environments.Content); i += 2 {
+			configs = append(configs, environments.Content[i])
+		}
+	}
+
+	for _, c := range configs {
+		for _, payload := range configPayloads(c) {
+			if p := mappingValue(payload, "path"); nonEmptyScalar(p) {
+				skipHeader := mappingValue(payload, "skipHeader")
+				refs[p] = skipHeader != nil && skipHeader.Value == "true"
+			}
+		}
+	}
+	return refs
+}
+
+// configPayloads returns the payload mapping nodes of a config or environment, a single payload or a list of them.
+func configPayloads(config *yaml3.Node) []*yaml3.Node {
+	payloads := mappingValue(config, "payload")
+	if payloads == nil {
+		return nil
+	}
+	if payloads.Kind == yaml3.MappingNode {
+		return []*yaml3.Node{payloads}
+	}
+	return payloads.Content
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StartAtAnnotation the Job annotation holding the time test workers start at, in RFC 3339 format.
+const StartAtAnnotation = "artillery.io/start-at"
+
+// StartBarrierTimeout how long test workers wait for each other before failing.
+const StartBarrierTimeout = 10 * time.Minute
+
+// startBarrierLead how far ahead the start time is set once all test workers are waiting,
+// leaving every worker time to see it.
+const startBarrierLead = 5 * time.Second
+
+// startBarrierContainer the name of the init container holding test workers until they all start.
+const startBarrierContainer = "start-barrier"
+
+// startBarrierScript the Node.js script run by the start barrier init container, using the Kubernetes API.
+// It waits for the start time annotation of the Job, then sleeps until that time.
+// Without an annotation, once all test workers are waiting, the first worker to notice sets it.
+// The Job's resourceVersion guards against workers setting different start times.
+const startBarrierScript = `
+const fs = require('fs');
+const https = require('https');
+const sa = '/var/run/secrets/kubernetes.io/serviceaccount';
+const token = fs.readFileSync(sa + '/token', 'utf8').trim();
+const ca = fs.readFileSync(sa + '/ca.crt');
+const ns = fs.readFileSync(sa + '/namespace', 'utf8').trim();
+const job = process.env.JOB_NAME;
+const count = parseInt(process.env.WORKER_COUNT, 10);
+const annotation = process.env.START_AT_ANNOTATION;
+const lead = parseInt(process.env.START_LEAD_MS, 10);
+const deadline = Date.now() + parseInt(process.env.BARRIER_TIMEOUT_MS, 10);
+
+const api = (method, path, body) => new Promise((resolve, reject) => {
+  const req = https.request({
+    host: process.env.KUBERNETES_SERVICE_HOST, port: process.env.KUBERNETES_SERVICE_PORT, path, method, ca,
+    headers: {'Authorization': 'Bearer ' + token, 'Content-Type': 'application/merge-patch+json'},
+  }, res => {
+    let data = '';
+    res.on('data', chunk => data += chunk);
+    res.on('end', () => resolve({status: res.statusCode, body: data ? JSON.parse(data) : {}}));
+  });
+  req.on('error', reject);
+  if (body) req.write(JSON.stringify(body));
+  req.end();
+});
+const sleep = ms => new Promise(resolve => setTimeout(resolve, ms));
+const fail = msg => { console.error(msg); process.exit(1); };
+
+(async () => {
+  const jobPath = '/apis/batch/v1/namespaces/' + ns + '/jobs/' + job;
+  while (Date.now() < deadline) {
+    const res = await api('GET', jobPath);
+    if (res.status !== 200) fail('cannot get job ' + job + ': ' + (res.body.message || res.status));
+
+    const startAt = (res.body.metadata.annotations || {})[annotation];
+    if (startAt) {
+      const at = Date.parse(startAt);
+      if (isNaN(at)) fail('invalid ' + annotation + ' annotation ' + startAt + ', use RFC 3339, e.g. 2022-06-01T10:00:00Z');
+      console.log('starting at ' + startAt);
+      await sleep(Math.max(0, at - Date.now()));
+      return;
+    }
+
+    const pods = await api('GET', '/api/v1/namespaces/' + ns + '/pods?labelSelector=job-name%3D' + job);
+    if (pods.status !== 200) fail('cannot list pods of job ' + job + ': ' + (pods.body.message || pods.status));
+    const waiting = pods.body.items.filter(p => (p.status.initContainerStatuses || [])
+      .some(s => s.name === '` + startBarrierContainer + `' && s.state.running)).length;
+    console.log(waiting + '/' + count + ' workers waiting');
+
+    if (waiting >= count) {
+      const at = new Date(Date.now() + lead).toISOString();
+      await api('PATCH', jobPath, {metadata: {resourceVersion: res.body.metadata.resourceVersion, annotations: {[annotation]: at}}});
+      continue;
+    }
+    await sleep(1000);
+  }
+  fail('timed out waiting for ' + count + ' workers to start');
+})().catch(err => fail(err));
+`
+
+// WithStartBarrier holds test workers in an init container until they all start at the same time.
+// The start time is the Job's StartAtAnnotation, set when all workers are waiting unless already set.
+// The init container runs the worker image, so test workers start without pulling it, and gets the worker resources.
+// The Pods use a ServiceAccount allowed to read and annotate the Job, see: NewStartBarrierRBAC.
+func (j *Job) WithStartBarrier(serviceAccountName string) *Job {
+	spec := &j.Spec.Template.Spec
+	spec.ServiceAccountName = serviceAccountName
+
+	worker := spec.Containers[0]
+	spec.InitContainers = append(spec.InitContainers, corev1.Container{
+		Name:            startBarrierContainer,
+		Image:           worker.Image,
+		ImagePullPolicy: worker.ImagePullPolicy,
+		Command:         []string{"node", "-e", startBarrierScript},
+		Resources:       worker.Resources,
+		Env: []corev1.EnvVar{
+			{Name: "JOB_NAME", Value: j.Name},
+			{Name: "WORKER_COUNT", Value: fmt.Sprint(*j.Spec.Completions)},
+			{Name: "START_AT_ANNOTATION", Value: StartAtAnnotation},
+			{Name: "START_LEAD_MS", Value: fmt.Sprint(startBarrierLead.Milliseconds())},
+			{Name: "BARRIER_TIMEOUT_MS", Value: fmt.Sprint(StartBarrierTimeout.Milliseconds())},
+		},
+	})
+	return j
+}
+
+// WithStartAt sets the time test workers held by a start barrier start at.
+func (j *Job) WithStartAt(startAt time.Time) *Job {
+	if startAt.IsZero() {
+		return j
+	}
+
+	if j.Annotations == nil {
+		j.Annotations = map[string]string{}
+	}
+	j.Annotations[StartAtAnnotation] = startAt.UTC().Format(time.RFC3339)
+	return j
+}
+
+// StartBarrierRBAC the ServiceAccount, Role and RoleBinding allowing test workers
+// to read and annotate their Job, and list its Pods.
+type StartBarrierRBAC struct {
+	ServiceAccount *corev1.ServiceAccount
+	Role           *rbacv1.Role
+	RoleBinding    *rbacv1.RoleBinding
+}
+
+// NewStartBarrierRBAC returns the StartBarrierRBAC of a test's start barrier, named after the test.
+func NewStartBarrierRBAC(testName, namespace string) *StartBarrierRBAC {
+	name := fmt.Sprintf("%s-start-barrier", testName)
+	meta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    labels(testName, "test-start-barrier"),
+	}
+
+	return &StartBarrierRBAC{
+		ServiceAccount: &corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{Kind: "ServiceAccount", APIVersion: "v1"},
+			ObjectMeta: meta,
+		},
+		Role: &rbacv1.Role{
+			TypeMeta:   metav1.TypeMeta{Kind: "Role", APIVersion: "rbac.authorization.k8s.io/v1"},
+			ObjectMeta: meta,
+			Rules: []rbacv1.PolicyRule{
+				{
+					APIGroups:     []string{"batch"},
+					Resources:     []string{"jobs"},
+					ResourceNames: []string{testName},
+					Verbs:         []string{"get", "patch"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"pods"},
+					Verbs:     []string{"list"},
+				},
+			},
+		},
+		RoleBinding: &rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{Kind: "RoleBinding", APIVersion: "rbac.authorization.k8s.io/v1"},
+			ObjectMeta: meta,
+			Subjects: []rbacv1.Subject{
+				{Kind: "ServiceAccount", Name: name, Namespace: namespace},
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: "rbac.authorization.k8s.io",
+				Kind:     "Role",
+				Name:     name,
+			},
+		},
+	}
+}
+
+// Name returns the name of the ServiceAccount, Role and RoleBinding.
+func (r *StartBarrierRBAC) Name() string {
+	return r.ServiceAccount.Name
+}
+
+// MarshalWithIndent marshals the StartBarrierRBAC objects as YAML documents using a specified indentation.
+func (r *StartBarrierRBAC) MarshalWithIndent(indent int) ([]byte, error) {
+	var docs [][]byte
+	for _, obj := range []interface{}{r.ServiceAccount, r.Role, r.RoleBinding} {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		var temp map[string]interface{}
+		if err := json.Unmarshal(data, &temp); err != nil {
+			return nil, err
+		}
+		delete(temp["metadata"].(map[string]interface{}), "creationTimestamp")
+
+		data, err = json.Marshal(temp)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := jsonToYaml(data, indent)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, y)
+	}
+	return bytes.Join(docs, []byte("---\n")), nil
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/artilleryio/kubectl-artillery/internal/telemetry"
+)
+
+func TestWithStartBarrierInitContainers(t *testing.T) {
+	large := []LargeAsset{{Name: "users.csv", Strategy: AssetStrategySplit, Parts: make([]AssetPart, 2)}}
+
+	tests := []struct {
+		name     string
+		packages []NpmPackage
+		large    []LargeAsset
+		want     []string
+	}{
+		{
+			name: "start barrier only",
+			want: []string{"start-barrier"},
+		},
+		{
+			name:     "after installing plugins",
+			packages: []NpmPackage{{Name: "ensure", Kind: "plugin"}},
+			want:     []string{"install-plugins", "start-barrier"},
+		},
+		{
+			name:  "after staging assets",
+			large: large,
+			want:  []string{"stage-assets", "start-barrier"},
+		},
+		{
+			name:     "after installing plugins and staging assets",
+			packages: []NpmPackage{{Name: "ensure", Kind: "plugin"}},
+			large:    large,
+			want:     []string{"install-plugins", "stage-assets", "start-barrier"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := NewTestJob("orders", "shop", "orders-test-script", "test-script.yaml", 2, telemetry.Config{}).
+				WithPackages(tt.packages).
+				WithLargeAssets(tt.large, "").
+				WithStartBarrier("orders-start-barrier")
+
+			var got []string
+			for _, c := range job.Spec.Template.Spec.InitContainers {
+				got = append(got, c.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("init containers = %v, want %v", got, tt.want)
+			}
+			if sa := job.Spec.Template.Spec.ServiceAccountName; sa != "orders-start-barrier" {
+				t.Errorf("service account = %q, want orders-start-barrier", sa)
+			}
+		})
+	}
+}
+
+func TestNewStartBarrierRBAC(t *testing.T) {
+	rbac := NewStartBarrierRBAC("orders", "shop")
+	if rbac.Name() != "orders-start-barrier" {
+		t.Errorf("Name() = %q, want orders-start-barrier", rbac.Name())
+	}
+
+	tests := []struct {
+		resource      string
+		resourceNames []string
+		verbs         []string
+	}{
+		{resource: "jobs", resourceNames: []string{"orders"}, verbs: []string{"get", "patch"}},
+		{resource: "pods", verbs: []string{"list"}},
+	}
+
+	if len(rbac.Role.Rules) != len(tests) {
+		t.Fatalf("got %d rules, want %d", len(rbac.Role.Rules), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			rule := rbac.Role.Rules[i]
+			if !reflect.DeepEqual(rule.Resources, []string{tt.resource}) {
+				t.Errorf("resources = %v, want %s", rule.Resources, tt.resource)
+			}
+			if !reflect.DeepEqual(rule.ResourceNames, tt.resourceNames) {
+				t.Errorf("resource names = %v, want %v", rule.ResourceNames, tt.resourceNames)
+			}
+			if !reflect.DeepEqual(rule.Verbs, tt.verbs) {
+				t.Errorf("verbs = %v, want %v", rule.Verbs, tt.verbs)
+			}
+		})
+	}
+
+	binding := rbac.RoleBinding
+	if binding.RoleRef.Name != rbac.Name() || len(binding.Subjects) != 1 ||
+		binding.Subjects[0].Name != rbac.Name() || binding.Subjects[0].Namespace != "shop" {
+		t.Errorf("role binding = %+v, want binding the ServiceAccount to the Role", binding)
+	}
+}
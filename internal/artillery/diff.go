@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext the number of unchanged lines shown around changed lines.
+const diffContext = 3
+
+// diffLine a line of a line-based diff, with its operation: ' ' unchanged, '-' removed or '+' added.
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// Diff returns a unified diff of two files' contents, or an empty string when both are the same.
+func Diff(path string, before, after []byte) string {
+	a := splitLines(string(before))
+	b := splitLines(string(after))
+	lines := diffLines(a, b)
+
+	var hunks strings.Builder
+	for start := 0; start < len(lines); {
+		// find the next changed line
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+
+		// extend the hunk until changes are separated by more than twice the context
+		from := maxInt(first-diffContext, start)
+		to := first
+		for unchanged := 0; to < len(lines) && unchanged <= 2*diffContext; to++ {
+			if lines[to].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		to = minInt(trimTrailingUnchanged(lines, to)+diffContext, len(lines))
+
+		aStart, bStart := lineNumbers(lines, from)
+		aCount, bCount := 0, 0
+		var body strings.Builder
+		for _, l := range lines[from:to] {
+			if l.op != '+' {
+				aCount++
+			}
+			if l.op != '-' {
+				bCount++
+			}
+			body.WriteString(fmt.Sprintf("%c%s\n", l.op, l.text))
+		}
+
+		hunks.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount))
+		hunks.WriteString(body.String())
+		start = to
+	}
+
+	if hunks.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("--- %s\n+++ %s\n%s", path, path, hunks.String())
+}
+
+// splitLines splits text into lines, ignoring a trailing newline.
+func splitLines(text string) []string {
+	if len(text) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the line operations turning a into b, using their longest common subsequence.
+func diffLines(a, b []string) []diffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = maxInt(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, diffLine{'-', a[i]})
+			i++
+		default:
+			out = append(out, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, diffLine{'+', b[j]})
+	}
+	return out
+}
+
+// trimTrailingUnchanged returns the end of a hunk's changed lines, excluding trailing unchanged lines.
+func trimTrailingUnchanged(lines []diffLine, end int) int {
+	for end > 0 && lines[end-1].op == ' ' {
+		end--
+	}
+	return end
+}
+
+// lineNumbers returns the 1-based line numbers in a and b of a diff line.
+func lineNumbers(lines []diffLine, at int) (int, int) {
+	aLine, bLine := 1, 1
+	for _, l := range lines[:at] {
+		if l.op != '+' {
+			aLine++
+		}
+		if l.op != '-' {
+			bLine++
+		}
+	}
+	return aLine, bLine
+}
+
+// maxInt returns the larger of two ints.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// minInt returns the smaller of two ints.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
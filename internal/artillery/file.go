@@ -16,6 +16,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // DirOrFileExists checks whether a directory or file exists.
@@ -56,3 +57,25 @@ func CopyFileTo(dir string, srcPath string) error {
 	}
 	return nil
 }
+
+// lineEdit replaces the first occurrence of old text with new text on a 1-based line.
+type lineEdit struct {
+	line int
+	old  string
+	new  string
+}
+
+// applyLineEdits applies line edits to a file's contents, keeping all other text and formatting as is.
+// Returns the edited contents and the number of applied edits, edits whose old text is not on their line are skipped.
+func applyLineEdits(data []byte, edits []lineEdit) ([]byte, int) {
+	lines := strings.Split(string(data), "\n")
+	applied := 0
+	for _, e := range edits {
+		if e.line < 1 || e.line > len(lines) || !strings.Contains(lines[e.line-1], e.old) {
+			continue
+		}
+		lines[e.line-1] = strings.Replace(lines[e.line-1], e.old, e.new, 1)
+		applied++
+	}
+	return []byte(strings.Join(lines, "\n")), applied
+}
@@ -0,0 +1,209 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"fmt"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Section defines a test script's before or after section, running a flow once before or after all scenarios.
+type Section struct {
+	Flows []Flow `json:"flow,omitempty" yaml:"flow,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
+
+// Flow defines a test script's flow step.
+// A step is a request, an engine specific step, a loop, a think time, a function call or a log message.
+// Steps of custom engines, e.g. grpc, are kept in Extra.
+type Flow struct {
+	Get     *RequestFlow `json:"get,omitempty" yaml:"get,omitempty"`
+	Post    *RequestFlow `json:"post,omitempty" yaml:"post,omitempty"`
+	Put     *RequestFlow `json:"put,omitempty" yaml:"put,omitempty"`
+	Patch   *RequestFlow `json:"patch,omitempty" yaml:"patch,omitempty"`
+	Delete  *RequestFlow `json:"delete,omitempty" yaml:"delete,omitempty"`
+	Head    *RequestFlow `json:"head,omitempty" yaml:"head,omitempty"`
+	Options *RequestFlow `json:"options,omitempty" yaml:"options,omitempty"`
+	Send    interface{}  `json:"send,omitempty" yaml:"send,omitempty"`
+	Emit    *EmitFlow    `json:"emit,omitempty" yaml:"emit,omitempty"`
+	Think   IntOrString  `json:"think,omitempty" yaml:"think,omitempty"`
+
+	PublishMessage *PublishMessageFlow `json:"publishMessage,omitempty" yaml:"publishMessage,omitempty"`
+
+	Loop      []Flow      `json:"loop,omitempty" yaml:"loop,omitempty"`
+	Count     IntOrString `json:"count,omitempty" yaml:"count,omitempty"`
+	Over      interface{} `json:"over,omitempty" yaml:"over,omitempty"`
+	WhileTrue string      `json:"whileTrue,omitempty" yaml:"whileTrue,omitempty"`
+	Function  string      `json:"function,omitempty" yaml:"function,omitempty"`
+	Log       string      `json:"log,omitempty" yaml:"log,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
+
+// Requests returns a flow step's requests by HTTP method, including requests in loops.
+func (f Flow) Requests() map[string][]*RequestFlow {
+	requests := map[string][]*RequestFlow{}
+	for method, req := range map[string]*RequestFlow{
+		"get":     f.Get,
+		"post":    f.Post,
+		"put":     f.Put,
+		"patch":   f.Patch,
+		"delete":  f.Delete,
+		"head":    f.Head,
+		"options": f.Options,
+	} {
+		if req != nil {
+			requests[method] = append(requests[method], req)
+		}
+	}
+
+	for _, step := range f.Loop {
+		for method, reqs := range step.Requests() {
+			requests[method] = append(requests[method], reqs...)
+		}
+	}
+	return requests
+}
+
+// PublishMessageFlow defines a test script's Kafka engine publish message flow.
+type PublishMessageFlow struct {
+	Topic string `json:"topic" yaml:"topic"`
+	Data  string `json:"data,omitempty" yaml:"data,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
+
+// EmitFlow defines a test script's Socket.io emit flow.
+// The list form [channel, data] is read into Channel and Data, and kept when marshaled.
+type EmitFlow struct {
+	Channel     string                 `json:"channel" yaml:"channel"`
+	Data        interface{}            `json:"data,omitempty" yaml:"data,omitempty"`
+	Namespace   string                 `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Response    map[string]interface{} `json:"response,omitempty" yaml:"response,omitempty"`
+	Acknowledge map[string]interface{} `json:"acknowledge,omitempty" yaml:"acknowledge,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+	list  bool
+}
+
+// emitFlow avoids recursing into EmitFlow.UnmarshalYAML and EmitFlow.MarshalYAML.
+type emitFlow EmitFlow
+
+// MarshalYAML marshals an EmitFlow in its list form when parsed from it, otherwise as a mapping.
+func (e EmitFlow) MarshalYAML() (interface{}, error) {
+	if !e.list {
+		return emitFlow(e), nil
+	}
+	if e.Data == nil {
+		return []interface{}{e.Channel}, nil
+	}
+	return []interface{}{e.Channel, e.Data}, nil
+}
+
+// UnmarshalYAML unmarshals an EmitFlow from its mapping or list form.
+func (e *EmitFlow) UnmarshalYAML(value *yaml3.Node) error {
+	if value.Kind != yaml3.SequenceNode {
+		return value.Decode((*emitFlow)(e))
+	}
+
+	var list []interface{}
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	if len(list) == 0 || len(list) > 2 {
+		return fmt.Errorf("line %d: emit expects a channel and optional data", value.Line)
+	}
+
+	*e = EmitFlow{Channel: fmt.Sprintf("%v", list[0]), list: true}
+	if len(list) == 2 {
+		e.Data = list[1]
+	}
+	return nil
+}
+
+// RequestFlow defines a test script's HTTP request flow.
+// Requests run conditionally when IfTrue is set, and can capture or match response values.
+type RequestFlow struct {
+	Url            string                 `json:"url,omitempty" yaml:"url,omitempty"`
+	Name           string                 `json:"name,omitempty" yaml:"name,omitempty"`
+	Headers        map[string]interface{} `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Cookie         map[string]interface{} `json:"cookie,omitempty" yaml:"cookie,omitempty"`
+	Qs             map[string]interface{} `json:"qs,omitempty" yaml:"qs,omitempty"`
+	Json           interface{}            `json:"json,omitempty" yaml:"json,omitempty"`
+	Form           map[string]interface{} `json:"form,omitempty" yaml:"form,omitempty"`
+	FormData       map[string]interface{} `json:"formData,omitempty" yaml:"formData,omitempty"`
+	Body           interface{}            `json:"body,omitempty" yaml:"body,omitempty"`
+	Auth           *Auth                  `json:"auth,omitempty" yaml:"auth,omitempty"`
+	Gzip           *bool                  `json:"gzip,omitempty" yaml:"gzip,omitempty"`
+	FollowRedirect *bool                  `json:"followRedirect,omitempty" yaml:"followRedirect,omitempty"`
+	IfTrue         string                 `json:"ifTrue,omitempty" yaml:"ifTrue,omitempty"`
+	Capture        Captures               `json:"capture,omitempty" yaml:"capture,omitempty"`
+	Match          Captures               `json:"match,omitempty" yaml:"match,omitempty"`
+	BeforeRequest  Functions              `json:"beforeRequest,omitempty" yaml:"beforeRequest,omitempty"`
+	AfterResponse  Functions              `json:"afterResponse,omitempty" yaml:"afterResponse,omitempty"`
+	Expect         []Expectation          `json:"expect,omitempty" yaml:"expect,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
+
+// Auth defines a request's basic authentication.
+type Auth struct {
+	User string `json:"user" yaml:"user"`
+	Pass string `json:"pass,omitempty" yaml:"pass,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
+
+// Capture defines a value captured from, or matched against, a response.
+// The value is selected using one of json, xpath, regexp, header or selector.
+type Capture struct {
+	Json      string      `json:"json,omitempty" yaml:"json,omitempty"`
+	XPath     string      `json:"xpath,omitempty" yaml:"xpath,omitempty"`
+	Regexp    string      `json:"regexp,omitempty" yaml:"regexp,omitempty"`
+	Header    string      `json:"header,omitempty" yaml:"header,omitempty"`
+	Selector  string      `json:"selector,omitempty" yaml:"selector,omitempty"`
+	Attr      string      `json:"attr,omitempty" yaml:"attr,omitempty"`
+	Group     IntOrString `json:"group,omitempty" yaml:"group,omitempty"`
+	Flags     string      `json:"flags,omitempty" yaml:"flags,omitempty"`
+	As        string      `json:"as,omitempty" yaml:"as,omitempty"`
+	Value     interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+	Strict    *bool       `json:"strict,omitempty" yaml:"strict,omitempty"`
+	Transform string      `json:"transform,omitempty" yaml:"transform,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
+
+// Expectation an expect plugin expectation for a test script's request flow.
+// StatusCode is a single status code or a list of accepted status codes.
+// See: https://www.artillery.io/docs/guides/plugins/plugin-expectations-assertions
+type Expectation struct {
+	StatusCode     interface{}   `json:"statusCode,omitempty" yaml:"statusCode,omitempty"`
+	ContentType    string        `json:"contentType,omitempty" yaml:"contentType,omitempty"`
+	HasProperty    string        `json:"hasProperty,omitempty" yaml:"hasProperty,omitempty"`
+	NotHasProperty string        `json:"notHasProperty,omitempty" yaml:"notHasProperty,omitempty"`
+	HasHeader      string        `json:"hasHeader,omitempty" yaml:"hasHeader,omitempty"`
+	Equals         []interface{} `json:"equals,omitempty" yaml:"equals,omitempty"`
+	MatchesRegexp  string        `json:"matchesRegexp,omitempty" yaml:"matchesRegexp,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
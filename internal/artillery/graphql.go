@@ -0,0 +1,350 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// graphQLSelectionDepth the depth of nested object fields selected by scaffolded queries.
+const graphQLSelectionDepth = 2
+
+// GraphQLSchema a GraphQL schema read from an introspection query result.
+// See: https://graphql.org/learn/introspection/
+type GraphQLSchema struct {
+	QueryType *struct {
+		Name string `json:"name"`
+	} `json:"queryType"`
+	Types []graphQLType `json:"types"`
+}
+
+// graphQLType a GraphQL schema named type.
+type graphQLType struct {
+	Kind        string              `json:"kind"`
+	Name        string              `json:"name"`
+	Fields      []graphQLField      `json:"fields"`
+	InputFields []graphQLInputValue `json:"inputFields"`
+	EnumValues  []struct {
+		Name string `json:"name"`
+	} `json:"enumValues"`
+}
+
+// graphQLField a GraphQL object type field.
+type graphQLField struct {
+	Name string              `json:"name"`
+	Args []graphQLInputValue `json:"args"`
+	Type graphQLTypeRef      `json:"type"`
+}
+
+// graphQLInputValue a GraphQL field argument or input object field.
+type graphQLInputValue struct {
+	Name string         `json:"name"`
+	Type graphQLTypeRef `json:"type"`
+}
+
+// graphQLTypeRef a GraphQL type reference, wrapped by NON_NULL and LIST types.
+type graphQLTypeRef struct {
+	Kind   string          `json:"kind"`
+	Name   *string         `json:"name"`
+	OfType *graphQLTypeRef `json:"ofType"`
+}
+
+// ReadGraphQLSchema reads a GraphQL schema from an introspection query result JSON file.
+// Both {"data": {"__schema": ...}} and {"__schema": ...} results are supported.
+func ReadGraphQLSchema(path string) (*GraphQLSchema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Schema *GraphQLSchema `json:"__schema"`
+		Data   struct {
+			Schema *GraphQLSchema `json:"__schema"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("cannot read graphql schema %s: %w", path, err)
+	}
+
+	schema := result.Schema
+	if schema == nil {
+		schema = result.Data.Schema
+	}
+
+	if schema == nil || schema.QueryType == nil {
+		return nil, fmt.Errorf("cannot read graphql schema %s: missing introspection __schema.queryType", path)
+	}
+	if err := schema.validate(); err != nil {
+		return nil, fmt.Errorf("cannot read graphql schema %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// validate checks the type references of every field, argument and input field are complete,
+// as partial introspection results may leave out wrapped types.
+func (s *GraphQLSchema) validate() error {
+	for _, t := range s.Types {
+		for _, field := range t.Fields {
+			if err := field.Type.validate(); err != nil {
+				return fmt.Errorf("%s.%s: %w", t.Name, field.Name, err)
+			}
+			for _, arg := range field.Args {
+				if err := arg.Type.validate(); err != nil {
+					return fmt.Errorf("%s.%s(%s): %w", t.Name, field.Name, arg.Name, err)
+				}
+			}
+		}
+		for _, field := range t.InputFields {
+			if err := field.Type.validate(); err != nil {
+				return fmt.Errorf("%s.%s: %w", t.Name, field.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// WithGraphQLQueries adds flows POSTing a representative query for every root Query field of a GraphQL schema.
+// Query arguments are passed as variables, using placeholders added to the test script's config.variables.
+// Every query is expected to return data and no errors.
+func (t *TestScript) WithGraphQLQueries(schema *GraphQLSchema, endpoint *url.URL) (*TestScript, error) {
+	queryType := schema.namedType(schema.QueryType.Name)
+	if queryType == nil || len(queryType.Fields) == 0 {
+		return nil, errors.New("graphql schema has no root Query fields")
+	}
+
+	if len(t.Config.Target) == 0 {
+		t.Config.Target = fmt.Sprintf("%s://%s/", endpoint.Scheme, endpoint.Host)
+	}
+
+	if t.Config.Variables == nil {
+		t.Config.Variables = map[string]interface{}{}
+	}
+
+	for _, field := range queryType.Fields {
+		query, variables := schema.query(field)
+		for name, placeholder := range variables {
+			t.Config.Variables[name] = []interface{}{placeholder}
+		}
+
+		body := map[string]interface{}{"query": query}
+		if len(field.Args) > 0 {
+			vars := map[string]interface{}{}
+			for _, arg := range field.Args {
+				vars[arg.Name] = fmt.Sprintf("{{ %s }}", graphQLVariableName(field, arg))
+			}
+			body["variables"] = vars
+		}
+
+		t.Scenarios[0].Flows = append(t.Scenarios[0].Flows, Flow{
+			Post: &RequestFlow{
+				Url:  endpoint.String(),
+				Json: body,
+				Expect: []Expectation{
+					{StatusCode: 200},
+					{HasProperty: "data"},
+					{NotHasProperty: "errors"},
+				},
+			},
+		})
+	}
+
+	return t, nil
+}
+
+// query returns a representative query for a root Query field,
+// along with placeholder values for the field's arguments keyed by test script variable name.
+// e.g. query order($id: ID!) { order(id: $id) { id total } }
+func (s *GraphQLSchema) query(field graphQLField) (string, map[string]interface{}) {
+	variables := map[string]interface{}{}
+
+	var declarations, args []string
+	for _, arg := range field.Args {
+		declarations = append(declarations, fmt.Sprintf("$%s: %s", arg.Name, arg.Type.String()))
+		args = append(args, fmt.Sprintf("%s: $%s", arg.Name, arg.Name))
+		variables[graphQLVariableName(field, arg)] = s.placeholder(arg.Type, 0)
+	}
+
+	var b strings.Builder
+	b.WriteString("query " + field.Name)
+	if len(declarations) > 0 {
+		b.WriteString("(" + strings.Join(declarations, ", ") + ")")
+	}
+	b.WriteString(" { " + field.Name)
+	if len(args) > 0 {
+		b.WriteString("(" + strings.Join(args, ", ") + ")")
+	}
+	b.WriteString(s.selectionSet(field.Type, graphQLSelectionDepth))
+	b.WriteString(" }")
+
+	return b.String(), variables
+}
+
+// selectionSet returns the selection set for a field's type.
+// Selects scalar and enum fields, along with nested object fields down to a depth.
+// Fields requiring arguments are skipped. Scalar and enum types need no selection set.
+func (s *GraphQLSchema) selectionSet(ref graphQLTypeRef, depth int) string {
+	t := s.namedType(ref.namedType())
+	if t == nil {
+		return ""
+	}
+
+	switch t.Kind {
+	case "UNION", "INTERFACE":
+		return " { __typename }"
+	case "OBJECT":
+	default:
+		return ""
+	}
+
+	var selected []string
+	for _, field := range t.Fields {
+		if hasRequiredArgs(field) {
+			continue
+		}
+
+		fieldType := s.namedType(field.Type.namedType())
+		if fieldType == nil || fieldType.Kind == "SCALAR" || fieldType.Kind == "ENUM" {
+			selected = append(selected, field.Name)
+			continue
+		}
+
+		if depth > 1 {
+			if nested := s.selectionSet(field.Type, depth-1); len(nested) > 0 {
+				selected = append(selected, field.Name+nested)
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		return " { __typename }"
+	}
+	return " { " + strings.Join(selected, " ") + " }"
+}
+
+// placeholder returns a placeholder value for an argument type.
+func (s *GraphQLSchema) placeholder(ref graphQLTypeRef, depth int) interface{} {
+	switch ref.Kind {
+	case "NON_NULL":
+		return s.placeholder(*ref.OfType, depth)
+	case "LIST":
+		return []interface{}{s.placeholder(*ref.OfType, depth)}
+	}
+
+	name := ref.namedType()
+	switch name {
+	case "Int":
+		return 1
+	case "Float":
+		return 1.0
+	case "Boolean":
+		return true
+	case "ID", "String":
+		return fmt.Sprintf("REPLACE_WITH_%s", strings.ToUpper(name))
+	}
+
+	t := s.namedType(name)
+	if t == nil {
+		return fmt.Sprintf("REPLACE_WITH_%s", name)
+	}
+
+	switch t.Kind {
+	case "ENUM":
+		if len(t.EnumValues) > 0 {
+			return t.EnumValues[0].Name
+		}
+	case "INPUT_OBJECT":
+		out := map[string]interface{}{}
+		if depth > graphQLSelectionDepth {
+			return out
+		}
+		for _, field := range t.InputFields {
+			if field.Type.Kind == "NON_NULL" {
+				out[field.Name] = s.placeholder(field.Type, depth+1)
+			}
+		}
+		return out
+	}
+	return fmt.Sprintf("REPLACE_WITH_%s", name)
+}
+
+// namedType returns a schema's named type.
+func (s *GraphQLSchema) namedType(name string) *graphQLType {
+	for i := range s.Types {
+		if s.Types[i].Name == name {
+			return &s.Types[i]
+		}
+	}
+	return nil
+}
+
+// namedType returns the name of the type wrapped by NON_NULL and LIST types.
+func (r graphQLTypeRef) namedType() string {
+	if r.OfType != nil {
+		return r.OfType.namedType()
+	}
+	if r.Name != nil {
+		return *r.Name
+	}
+	return ""
+}
+
+// validate checks NON_NULL and LIST types wrap a type, and other types are named.
+func (r graphQLTypeRef) validate() error {
+	switch r.Kind {
+	case "NON_NULL", "LIST":
+		if r.OfType == nil {
+			return fmt.Errorf("%s type has no ofType", r.Kind)
+		}
+		return r.OfType.validate()
+	}
+	if r.Name == nil || len(*r.Name) == 0 {
+		return fmt.Errorf("%s type has no name", r.Kind)
+	}
+	return nil
+}
+
+// String returns a type reference using GraphQL syntax, e.g. [ID!]!
+func (r graphQLTypeRef) String() string {
+	if r.OfType == nil {
+		return r.namedType()
+	}
+
+	switch r.Kind {
+	case "NON_NULL":
+		return r.OfType.String() + "!"
+	case "LIST":
+		return "[" + r.OfType.String() + "]"
+	}
+	return r.namedType()
+}
+
+// hasRequiredArgs returns whether a field has any non-null arguments.
+func hasRequiredArgs(field graphQLField) bool {
+	for _, arg := range field.Args {
+		if arg.Type.Kind == "NON_NULL" {
+			return true
+		}
+	}
+	return false
+}
+
+// graphQLVariableName returns the test script variable name used for a root Query field's argument.
+func graphQLVariableName(field graphQLField, arg graphQLInputValue) string {
+	return fmt.Sprintf("%s_%s", field.Name, arg.Name)
+}
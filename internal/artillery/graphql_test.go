@@ -0,0 +1,78 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadGraphQLSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		wantErr string
+	}{
+		{
+			name: "complete",
+			args: `[{"name": "id", "type": {"kind": "NON_NULL", "ofType": {"kind": "SCALAR", "name": "ID"}}}]`,
+		},
+		{
+			name:    "non null without ofType",
+			args:    `[{"name": "id", "type": {"kind": "NON_NULL"}}]`,
+			wantErr: "Query.order(id): NON_NULL type has no ofType",
+		},
+		{
+			name:    "list of unnamed type",
+			args:    `[{"name": "ids", "type": {"kind": "LIST", "ofType": {"kind": "SCALAR"}}}]`,
+			wantErr: "Query.order(ids): SCALAR type has no name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "schema.json")
+			schema := `{"data": {"__schema": {"queryType": {"name": "Query"}, "types": [
+  {"kind": "OBJECT", "name": "Query", "fields": [
+    {"name": "order", "args": ` + tt.args + `, "type": {"kind": "SCALAR", "name": "String"}}
+  ]}
+]}}}`
+			if err := os.WriteFile(path, []byte(schema), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := ReadGraphQLSchema(path)
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Fatalf("ReadGraphQLSchema() error = %v", err)
+			}
+			if len(tt.wantErr) > 0 && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("ReadGraphQLSchema() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGraphQLTypeRefString(t *testing.T) {
+	id := "ID"
+	ref := graphQLTypeRef{Kind: "NON_NULL", OfType: &graphQLTypeRef{Kind: "LIST", OfType: &graphQLTypeRef{
+		Kind: "NON_NULL", OfType: &graphQLTypeRef{Kind: "SCALAR", Name: &id},
+	}}}
+	if got := ref.String(); got != "[ID!]!" {
+		t.Errorf("String() = %q, want [ID!]!", got)
+	}
+	if got := (graphQLTypeRef{Kind: "NON_NULL"}).String(); got != "" {
+		t.Errorf("String() of a partial type = %q, want empty", got)
+	}
+}
@@ -14,6 +14,8 @@ package artillery
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	"github.com/artilleryio/kubectl-artillery/internal/telemetry"
 	"k8s.io/api/batch/v1"
@@ -67,7 +69,7 @@ func NewTestJob(testName, namespace, configMapName, testScriptFilename string, c
 								VolumeMounts: []corev1.VolumeMount{
 									{
 										Name:      JobTestScriptVol,
-										MountPath: "/data",
+										MountPath: JobDataMountPath,
 									},
 								},
 								Args: []string{
@@ -116,6 +118,194 @@ func NewTestJob(testName, namespace, configMapName, testScriptFilename string, c
 	return j
 }
 
+// WithConfigFile runs the test using an external config file mounted under /data, see: artillery run --config.
+func (j *Job) WithConfigFile(filename string) *Job {
+	if len(filename) == 0 {
+		return j
+	}
+
+	return j.withRunArgs("--config", "/data/"+filename)
+}
+
+// WithRunOptions passes artillery run options to test workers.
+func (j *Job) WithRunOptions(opts RunOptions) *Job {
+	return j.withRunArgs(opts.args()...)
+}
+
+// withRunArgs adds artillery run arguments before the test script argument.
+func (j *Job) withRunArgs(args ...string) *Job {
+	if len(args) == 0 {
+		return j
+	}
+
+	container := &j.Spec.Template.Spec.Containers[0]
+	last := len(container.Args) - 1
+	container.Args = append(
+		append(container.Args[:last:last], args...),
+		container.Args[last],
+	)
+	return j
+}
+
+// WithLargeAssets stages large assets into a data volume mounted by test workers at /data, using an init container.
+// The init container copies the test script ConfigMap files into the data volume, then assembles large assets from their parts.
+// The data volume is an emptyDir, or a PersistentVolumeClaim when large assets use one.
+func (j *Job) WithLargeAssets(large []LargeAsset, claimName string) *Job {
+	if len(large) == 0 {
+		return j
+	}
+
+	spec := &j.Spec.Template.Spec
+	data := corev1.Volume{
+		Name:         JobDataVol,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+	if len(claimName) > 0 {
+		data.VolumeSource = corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+		}
+	}
+	spec.Volumes = append(spec.Volumes, data)
+
+	mounts := []corev1.VolumeMount{
+		{Name: JobTestScriptVol, MountPath: JobTestScriptMountPath},
+		{Name: JobDataVol, MountPath: JobDataMountPath},
+	}
+	for i, a := range large {
+		for k, part := range a.Parts {
+			spec.Volumes = append(spec.Volumes, corev1.Volume{
+				Name: assetVolumeName(i, k),
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: part.ConfigMap},
+					},
+				},
+			})
+			mounts = append(mounts, corev1.VolumeMount{Name: assetVolumeName(i, k), MountPath: assetMountPath(i, k)})
+		}
+	}
+
+	spec.InitContainers = append(spec.InitContainers, corev1.Container{
+		Name:         "stage-assets",
+		Image:        AssetsInitImage,
+		Command:      []string{"sh", "-c", stageCommand(large)},
+		VolumeMounts: mounts,
+	})
+
+	// test workers read the staged data volume instead of the test script ConfigMap
+	for i, m := range spec.Containers[0].VolumeMounts {
+		if m.Name == JobTestScriptVol {
+			spec.Containers[0].VolumeMounts[i].Name = JobDataVol
+		}
+	}
+	return j
+}
+
+// WithPayloadShards runs the test as an Indexed Job, where each test worker mounts its own shard of every sharded payload.
+// Shards packed into several ConfigMaps are mounted from a projected volume joining them.
+func (j *Job) WithPayloadShards(configMapNames []string, shards []PayloadShards) *Job {
+	if len(shards) == 0 {
+		return j
+	}
+
+	j.indexed()
+
+	volume := corev1.Volume{Name: JobShardsVol}
+	if len(configMapNames) == 1 {
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: configMapNames[0]},
+		}
+	} else {
+		volume.Projected = &corev1.ProjectedVolumeSource{}
+		for _, name := range configMapNames {
+			volume.Projected.Sources = append(volume.Projected.Sources, corev1.VolumeProjection{
+				ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+			})
+		}
+	}
+
+	spec := &j.Spec.Template.Spec
+	spec.Volumes = append(spec.Volumes, volume)
+
+	container := &spec.Containers[0]
+	for _, p := range shards {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:        JobShardsVol,
+			MountPath:   JobShardsMountPath + "/" + p.Name,
+			SubPathExpr: p.ShardSubPathExpr(),
+		})
+	}
+	return j
+}
+
+// WithWorkerScripts runs the test as an Indexed Job, where each test worker runs its own test script, see: Bundle.SplitLoad.
+// The worker script file name is expanded by Kubernetes from the WORKER_INDEX environment variable.
+func (j *Job) WithWorkerScripts(workerScript string) *Job {
+	if len(workerScript) == 0 {
+		return j
+	}
+
+	j.indexed()
+	container := &j.Spec.Template.Spec.Containers[0]
+	container.Args[len(container.Args)-1] = "/data/" + workerScript
+	return j
+}
+
+// indexed runs the test as an Indexed Job.
+// Test workers get their completion index as WORKER_INDEX, and the number of workers as WORKER_COUNT.
+func (j *Job) indexed() {
+	if j.Spec.CompletionMode != nil && *j.Spec.CompletionMode == v1.IndexedCompletion {
+		return
+	}
+
+	indexed := v1.IndexedCompletion
+	j.Spec.CompletionMode = &indexed
+
+	container := &j.Spec.Template.Spec.Containers[0]
+	container.Env = append(container.Env,
+		corev1.EnvVar{
+			Name: "WORKER_INDEX",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.annotations['batch.kubernetes.io/job-completion-index']",
+				},
+			},
+		},
+		corev1.EnvVar{
+			Name:  "WORKER_COUNT",
+			Value: fmt.Sprint(*j.Spec.Completions),
+		},
+	)
+}
+
+// WithImage sets the image test workers run, and the Secrets used to pull it from private registries.
+// Without a pull policy, images pinned to a digest are pulled if not present, others are always pulled.
+func (j *Job) WithImage(image string, pullPolicy corev1.PullPolicy, pullSecrets []string) *Job {
+	if len(pullPolicy) == 0 {
+		pullPolicy = corev1.PullAlways
+		if strings.Contains(image, "@") {
+			pullPolicy = corev1.PullIfNotPresent
+		}
+	}
+
+	spec := &j.Spec.Template.Spec
+	spec.Containers[0].Image = image
+	spec.Containers[0].ImagePullPolicy = pullPolicy
+	for _, secret := range pullSecrets {
+		spec.ImagePullSecrets = append(spec.ImagePullSecrets, corev1.LocalObjectReference{Name: secret})
+	}
+	return j
+}
+
+// ParsePullPolicy parses and validates an image pull policy, empty for the default policy.
+func ParsePullPolicy(s string) (corev1.PullPolicy, error) {
+	switch policy := corev1.PullPolicy(s); policy {
+	case "", corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown image pull policy %q, use Always, IfNotPresent or Never", s)
+}
+
 // labels creates K8s labels used to organize
 // and categorize (scope and select) test jobs.
 func labels(name string, component string) map[string]string {
@@ -16,7 +16,6 @@ import (
 	"bytes"
 	"fmt"
 	"log"
-	"path/filepath"
 
 	yaml3 "gopkg.in/yaml.v3"
 	"sigs.k8s.io/kustomize/api/types"
@@ -28,9 +27,8 @@ type Kustomization struct {
 }
 
 // NewKustomization returns a configured Kustomization wrapper for an Artillery test.
-func NewKustomization(testFilename, namespace, configMap, testScript, labelPrefix string) *Kustomization {
-	testScript = filepath.Base(testScript)
-
+// The test's bundled files, i.e. the test script and its assets, are generated into a single ConfigMap.
+func NewKustomization(testFilename, namespace, configMap string, files []string, labelPrefix string) *Kustomization {
 	k := &Kustomization{
 		Kustomization: &types.Kustomization{
 			Namespace: namespace,
@@ -43,7 +41,7 @@ func NewKustomization(testFilename, namespace, configMap, testScript, labelPrefi
 					GeneratorArgs: types.GeneratorArgs{
 						Name: configMap,
 						KvPairSources: types.KvPairSources{
-							FileSources: []string{testScript},
+							FileSources: files,
 						},
 					},
 				},
@@ -62,6 +60,36 @@ func NewKustomization(testFilename, namespace, configMap, testScript, labelPrefi
 	return k
 }
 
+// WithConfigMap adds a generated ConfigMap holding files, e.g. the parts of a large asset.
+func (k *Kustomization) WithConfigMap(name string, files []string) *Kustomization {
+	k.ConfigMapGenerator = append(k.ConfigMapGenerator, types.ConfigMapArgs{
+		GeneratorArgs: types.GeneratorArgs{
+			Name: name,
+			KvPairSources: types.KvPairSources{
+				FileSources: files,
+			},
+		},
+	})
+	return k
+}
+
+// WithImage adds an images entry setting the tag or digest of an image,
+// so overlays can swap the image by its name.
+func (k *Kustomization) WithImage(name, tag, digest string) *Kustomization {
+	image := types.Image{Name: name, NewTag: tag}
+	if len(digest) > 0 {
+		image = types.Image{Name: name, Digest: digest}
+	}
+	k.Images = append(k.Images, image)
+	return k
+}
+
+// WithResource adds a resource manifest file.
+func (k *Kustomization) WithResource(filename string) *Kustomization {
+	k.Resources = append(k.Resources, filename)
+	return k
+}
+
 // MarshalWithIndent marshals a Kustomization using a specified indentation.
 func (k *Kustomization) MarshalWithIndent(indent int) ([]byte, error) {
 	var out bytes.Buffer
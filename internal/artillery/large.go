@@ -0,0 +1,360 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MaxConfigMapSize the data size that fits in a single ConfigMap applied by kubectl apply.
+// Client-side apply copies the ConfigMap into its last-applied-configuration annotation, limited to 256 KiB,
+// so the limit leaves room for base64 encoded binary data, e.g. gzipped parts, and metadata.
+const MaxConfigMapSize = 180 * 1024
+
+// AssetStrategy how an asset too large for the test's ConfigMap is delivered to test workers.
+type AssetStrategy string
+
+const (
+	// AssetStrategyAuto gzips large assets, splitting them across ConfigMaps when still too large.
+	AssetStrategyAuto AssetStrategy = "auto"
+	// AssetStrategySplit splits large assets across ConfigMaps, joined by an init container.
+	AssetStrategySplit AssetStrategy = "split"
+	// AssetStrategyGzip gzips large assets into their own ConfigMap, decompressed by an init container.
+	AssetStrategyGzip AssetStrategy = "gzip"
+	// AssetStrategyPVC a cache layered on top of auto. Test workers still mount every ConfigMap part,
+	// an init container assembles an asset into a PersistentVolumeClaim kept between test runs only when it changed.
+	// The claim is shared by test workers, so it needs a storage class supporting ReadWriteMany for more than one worker.
+	AssetStrategyPVC AssetStrategy = "pvc"
+)
+
+// AssetStrategies all supported AssetStrategy values.
+var AssetStrategies = []AssetStrategy{AssetStrategyAuto, AssetStrategySplit, AssetStrategyGzip, AssetStrategyPVC}
+
+// LargeAsset an asset too large for the test's ConfigMap, stored in its own ConfigMaps as one or more parts.
+type LargeAsset struct {
+	Name     string
+	Size     int64
+	Strategy AssetStrategy
+	Gzipped  bool
+	Checksum string
+	Parts    []AssetPart
+}
+
+// AssetPart a file holding part of a LargeAsset, generated into its own ConfigMap.
+type AssetPart struct {
+	File      string
+	ConfigMap string
+}
+
+// String describes how a LargeAsset is delivered to a single test worker.
+func (a LargeAsset) String() string {
+	return a.Describe(1)
+}
+
+// Describe describes how a LargeAsset is delivered to a number of test workers,
+// including the ReadWriteMany access a PersistentVolumeClaim shared by several workers requires.
+func (a LargeAsset) Describe(workers int) string {
+	how := "split"
+	if a.Gzipped {
+		how = "gzip compressed"
+	}
+	configMaps := "its own ConfigMap"
+	if len(a.Parts) > 1 {
+		configMaps = fmt.Sprintf("%d ConfigMaps", len(a.Parts))
+	}
+	s := fmt.Sprintf("%s is %s, too large for the test's ConfigMap, %s into %s", a.Name, humanSize(a.Size), how, configMaps)
+	if a.Strategy == AssetStrategyPVC {
+		s += ", assembled once into a PersistentVolumeClaim caching it between test runs"
+		if workers > 1 {
+			s += fmt.Sprintf(", the claim is ReadWriteMany for %d workers, its storage class must support it", workers)
+		}
+	}
+	return s
+}
+
+// ParseAssetStrategy parses and validates an AssetStrategy.
+func ParseAssetStrategy(s string) (AssetStrategy, error) {
+	for _, strategy := range AssetStrategies {
+		if string(strategy) == s {
+			return strategy, nil
+		}
+	}
+
+	var names []string
+	for _, strategy := range AssetStrategies {
+		names = append(names, string(strategy))
+	}
+	return "", fmt.Errorf("unknown large asset strategy %q, use one of %s", s, strings.Join(names, ", "))
+}
+
+// StageLargeAssets moves assets too large for the test's ConfigMap into their own ConfigMaps using a strategy.
+// When all bundled files together are too large, the largest assets are moved until the rest fits.
+// The parts of large assets are written to the bundle directory, named after ConfigMaps using a prefix.
+func (b *Bundle) StageLargeAssets(strategy AssetStrategy, configMapPrefix string) error {
+	sizes := map[string]int64{}
+	var total int64
+	for _, f := range b.Files() {
+		info, err := os.Stat(filepath.Join(b.Dir, f))
+		if err != nil {
+			return err
+		}
+		sizes[f] = info.Size()
+		total += info.Size()
+	}
+
+	var assets []string
+	for _, a := range b.Assets {
+		if !b.IsSharded(a) {
+			assets = append(assets, a)
+		}
+	}
+	sort.SliceStable(assets, func(i, j int) bool { return sizes[assets[i]] > sizes[assets[j]] })
+
+	for _, name := range assets {
+		if total <= MaxConfigMapSize && sizes[name] <= MaxConfigMapSize {
+			continue
+		}
+
+		large, err := b.stageLargeAsset(name, strategy, fmt.Sprintf("%s-asset-%d", configMapPrefix, len(b.Large)))
+		if err != nil {
+			return err
+		}
+		b.Large = append(b.Large, large)
+		total -= sizes[name]
+	}
+
+	if total > MaxConfigMapSize {
+		return fmt.Errorf("the test script and config are %s, too large for a ConfigMap", humanSize(total))
+	}
+	return nil
+}
+
+// IsLarge checks whether a bundled file is a LargeAsset.
+func (b *Bundle) IsLarge(name string) bool {
+	for _, a := range b.Large {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesPVC checks whether large assets are assembled into a PersistentVolumeClaim.
+func (b *Bundle) UsesPVC() bool {
+	return len(b.Large) > 0 && b.Large[0].Strategy == AssetStrategyPVC
+}
+
+// stageLargeAsset writes the parts of a large asset to the bundle directory using a strategy.
+func (b *Bundle) stageLargeAsset(name string, strategy AssetStrategy, configMapPrefix string) (LargeAsset, error) {
+	path := filepath.Join(b.Dir, name)
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return LargeAsset{}, err
+	}
+
+	sum := sha256.Sum256(data)
+	large := LargeAsset{
+		Name:     name,
+		Size:     int64(len(data)),
+		Strategy: strategy,
+		Gzipped:  strategy != AssetStrategySplit,
+		Checksum: hex.EncodeToString(sum[:]),
+	}
+
+	content := data
+	if large.Gzipped {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return LargeAsset{}, err
+		}
+		if err := zw.Close(); err != nil {
+			return LargeAsset{}, err
+		}
+		content = buf.Bytes()
+	}
+
+	parts := (len(content) + MaxConfigMapSize - 1) / MaxConfigMapSize
+	if strategy == AssetStrategyGzip && parts > 1 {
+		return LargeAsset{}, fmt.Errorf("%s is %s gzip compressed, too large for a ConfigMap, use the auto or split strategy", name, humanSize(int64(len(content))))
+	}
+
+	for i := 0; i < parts; i++ {
+		file := name
+		if large.Gzipped {
+			file += ".gz"
+		}
+		if parts > 1 {
+			file = fmt.Sprintf("%s.part-%d", file, i)
+		}
+
+		end := (i + 1) * MaxConfigMapSize
+		if end > len(content) {
+			end = len(content)
+		}
+		if err := os.WriteFile(filepath.Join(b.Dir, file), content[i*MaxConfigMapSize:end], 0644); err != nil {
+			return LargeAsset{}, err
+		}
+
+		large.Parts = append(large.Parts, AssetPart{File: file, ConfigMap: fmt.Sprintf("%s-%d", configMapPrefix, i)})
+	}
+
+	// keep the source file when bundling into its own directory
+	if abs, _ := filepath.Abs(path); b.sources[name] != abs {
+		if err := os.Remove(path); err != nil {
+			return LargeAsset{}, err
+		}
+	}
+	return large, nil
+}
+
+// stageCommand returns the init container shell command copying the test's ConfigMap files to the data volume,
+// and assembling large assets from their parts. Assets already assembled into a PersistentVolumeClaim are skipped.
+// Test workers share the claim, so each worker writes to its own temporary file and renames it into place,
+// a rename being atomic workers never read a file another worker is still writing.
+func stageCommand(large []LargeAsset) string {
+	lines := []string{
+		"set -e",
+		fmt.Sprintf(
+			"for f in %s/*; do name=${f##*/}; cp -L \"$f\" %[2]s/.$name.$HOSTNAME; mv -f %[2]s/.$name.$HOSTNAME %[2]s/$name; done",
+			JobTestScriptMountPath, JobDataMountPath,
+		),
+	}
+
+	for i, a := range large {
+		var parts []string
+		for j, p := range a.Parts {
+			parts = append(parts, fmt.Sprintf("%s/%s", assetMountPath(i, j), p.File))
+		}
+
+		target := fmt.Sprintf("%s/%s", JobDataMountPath, a.Name)
+		tmp := fmt.Sprintf("%s/.%s.$HOSTNAME", JobDataMountPath, a.Name)
+		assemble := fmt.Sprintf("cat %s > %s; mv -f %s %s", strings.Join(parts, " "), tmp, tmp, target)
+		if a.Gzipped {
+			assemble = fmt.Sprintf("cat %s | gunzip > %s; mv -f %s %s", strings.Join(parts, " "), tmp, tmp, target)
+		}
+
+		if a.Strategy == AssetStrategyPVC {
+			marker := fmt.Sprintf("%s/.%s.sha256", JobDataMountPath, a.Name)
+			assemble = fmt.Sprintf(
+				"if [ \"$(cat %[1]s 2>/dev/null)\" != \"%[2]s\" ]; then %[3]s; echo %[2]s > %[1]s.$HOSTNAME; mv -f %[1]s.$HOSTNAME %[1]s; fi",
+				marker, a.Checksum, assemble,
+			)
+		}
+		lines = append(lines, assemble)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// assetVolumeName returns the volume name of a large asset part's ConfigMap.
+func assetVolumeName(asset, part int) string {
+	return fmt.Sprintf("asset-%d-%d", asset, part)
+}
+
+// assetMountPath returns the path a large asset part's ConfigMap is mounted at in the init container.
+func assetMountPath(asset, part int) string {
+	return "/assets/" + assetVolumeName(asset, part)
+}
+
+// PersistentVolumeClaim wrapper to enable marshaling a Kubernetes PersistentVolumeClaim to a file.
+type PersistentVolumeClaim struct {
+	*corev1.PersistentVolumeClaim
+}
+
+// NewAssetsPersistentVolumeClaim returns a PersistentVolumeClaim sized to hold a test's large assets.
+// Test workers share the claim, so it is ReadWriteMany when a test runs more than one worker.
+func NewAssetsPersistentVolumeClaim(name, namespace string, large []LargeAsset, count int) *PersistentVolumeClaim {
+	var size int64
+	for _, a := range large {
+		size += a.Size
+	}
+	// leave room for the test script, config and small assets
+	size = size*2 + 64*1024*1024
+
+	accessMode := corev1.ReadWriteOnce
+	if count > 1 {
+		accessMode = corev1.ReadWriteMany
+	}
+
+	return &PersistentVolumeClaim{
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaim{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "PersistentVolumeClaim",
+				APIVersion: "v1",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"artillery.io/component": fmt.Sprintf("%s-assets", LabelPrefix),
+					"artillery.io/part-of":   LabelPrefix,
+				},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceStorage: *resource.NewQuantity(size, resource.BinarySI),
+					},
+				},
+			},
+		},
+	}
+}
+
+// MarshalWithIndent marshals a PersistentVolumeClaim using a specified indentation.
+func (p *PersistentVolumeClaim) MarshalWithIndent(indent int) ([]byte, error) {
+	data, err := p.json()
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonToYaml(data, indent)
+}
+
+func (p *PersistentVolumeClaim) json() ([]byte, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var temp map[string]interface{}
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return nil, err
+	}
+	delete(temp, "status")
+	delete(temp["metadata"].(map[string]interface{}), "creationTimestamp")
+
+	return json.Marshal(temp)
+}
+
+// humanSize returns a size in bytes as KiB or MiB.
+func humanSize(size int64) string {
+	if size >= 1024*1024 {
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1024*1024))
+	}
+	return fmt.Sprintf("%.1f KiB", float64(size)/1024)
+}
@@ -0,0 +1,206 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// bundleFiles writes a test script and its files to a source directory, and bundles them into another directory.
+func bundleFiles(t *testing.T, script string, files map[string][]byte) *Bundle {
+	t.Helper()
+	src := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(src, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scriptPath := filepath.Join(src, "test-script.yaml")
+	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := BundleTestScript(t.TempDir(), scriptPath, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// payloadScript a test script reading users.csv.
+const payloadScript = `config:
+  target: http://orders
+  phases: [{duration: 1, arrivalRate: 1}]
+  payload:
+    path: users.csv
+    fields: [id]
+scenarios:
+  - flow:
+      - get: {url: "/users/{{ id }}"}
+`
+
+func TestStageLargeAssetsFitsLastAppliedConfiguration(t *testing.T) {
+	// random data does not compress, so gzipped parts are as large as the asset
+	data := make([]byte, 500*1024)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	b := bundleFiles(t, payloadScript, map[string][]byte{"users.csv": data})
+	if err := b.StageLargeAssets(AssetStrategyAuto, "orders-test-script"); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Large) != 1 || len(b.Large[0].Parts) < 3 {
+		t.Fatalf("large assets = %v, want users.csv in at least 3 parts", b.Large)
+	}
+
+	// binary ConfigMap data is base64 encoded in the last-applied-configuration annotation
+	const maxAnnotations = 256 * 1024
+	for _, part := range b.Large[0].Parts {
+		info, err := os.Stat(filepath.Join(b.Dir, part.File))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoded := base64.StdEncoding.EncodedLen(int(info.Size())); encoded+4096 > maxAnnotations {
+			t.Errorf("part %s is %d bytes base64 encoded, too large for kubectl apply", part.File, encoded)
+		}
+	}
+}
+
+func TestStageCommandSharedClaim(t *testing.T) {
+	data := make([]byte, 500*1024)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	b := bundleFiles(t, payloadScript, map[string][]byte{"users.csv": data})
+	if err := b.StageLargeAssets(AssetStrategyPVC, "orders-test-script"); err != nil {
+		t.Fatal(err)
+	}
+
+	// lay out the ConfigMap mounts of the init container under a temporary root
+	root := t.TempDir()
+	mounts := map[string]string{
+		JobTestScriptMountPath: filepath.Join(root, "test-script"),
+		JobDataMountPath:       filepath.Join(root, "data"),
+		"/assets":              filepath.Join(root, "assets"),
+	}
+	for _, dir := range mounts {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	copyFile(t, filepath.Join(b.Dir, b.Script), filepath.Join(mounts[JobTestScriptMountPath], b.Script))
+	for i, a := range b.Large {
+		for j, p := range a.Parts {
+			dir := filepath.Join(root, assetMountPath(i, j))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			copyFile(t, filepath.Join(b.Dir, p.File), filepath.Join(dir, p.File))
+		}
+	}
+
+	command := stageCommand(b.Large)
+	for path, dir := range mounts {
+		command = strings.ReplaceAll(command, path+"/", dir+"/")
+	}
+
+	// workers stage into the shared claim concurrently
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cmd := exec.Command("sh", "-c", command)
+			cmd.Env = append(os.Environ(), fmt.Sprintf("HOSTNAME=orders-test-script-%d", i))
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs[i] = fmt.Errorf("%w: %s", err, out)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("worker %d: %v", i, err)
+		}
+	}
+
+	staged, err := os.ReadFile(filepath.Join(mounts[JobDataMountPath], "users.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(staged, data) {
+		t.Errorf("staged users.csv differs from the bundled asset")
+	}
+
+	if _, err := os.Stat(filepath.Join(mounts[JobDataMountPath], b.Script)); err != nil {
+		t.Errorf("test script not staged: %v", err)
+	}
+
+	entries, err := os.ReadDir(mounts[JobDataMountPath])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), "orders-test-script-") {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStageLargeAssetsAutoNeverUsesPVC(t *testing.T) {
+	data := make([]byte, 2*1024*1024)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+
+	b := bundleFiles(t, payloadScript, map[string][]byte{"users.csv": data})
+	if err := b.StageLargeAssets(AssetStrategyAuto, "orders-test-script"); err != nil {
+		t.Fatal(err)
+	}
+	if b.UsesPVC() {
+		t.Errorf("auto strategy uses a PersistentVolumeClaim for %s", b.Large[0])
+	}
+}
+
+func TestLargeAssetDescribe(t *testing.T) {
+	a := LargeAsset{Name: "users.csv", Size: 2 * 1024 * 1024, Strategy: AssetStrategyPVC, Gzipped: true, Parts: make([]AssetPart, 12)}
+	if s := a.Describe(1); strings.Contains(s, "ReadWriteMany") {
+		t.Errorf("Describe(1) = %q, a single worker needs no ReadWriteMany claim", s)
+	}
+	if s := a.Describe(4); !strings.Contains(s, "ReadWriteMany for 4 workers") {
+		t.Errorf("Describe(4) = %q, want the ReadWriteMany requirement", s)
+	}
+}
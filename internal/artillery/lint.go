@@ -0,0 +1,390 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/artilleryio/kubectl-artillery/internal/kube"
+	yaml3 "gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultMaxWorkerArrivalRate the arrival rate of new virtual users per second a single worker realistically sustains.
+const DefaultMaxWorkerArrivalRate = 250
+
+// Lint rules.
+const (
+	LintRuleSchema      = "schema"
+	LintRuleTarget      = "target-service"
+	LintRulePort        = "target-port"
+	LintRuleArrivalRate = "arrival-rate"
+	LintRulePlugin      = "plugin"
+	LintRuleEngine      = "engine"
+)
+
+// LintIssue a test script problem at a file position, mechanical issues can be fixed.
+type LintIssue struct {
+	Path    string
+	Line    int
+	Column  int
+	Rule    string
+	Message string
+	fix     *lintFix
+}
+
+// lintFix replaces text on an issue's line.
+type lintFix struct {
+	old string
+	new string
+}
+
+// Fixable checks whether a LintIssue can be fixed mechanically.
+func (i LintIssue) Fixable() bool {
+	return i.fix != nil
+}
+
+// String returns a LintIssue as file:line:column: rule: message.
+func (i LintIssue) String() string {
+	s := fmt.Sprintf("%s:%d:%d: %s: %s", i.Path, i.Line, i.Column, i.Rule, i.Message)
+	if i.Fixable() {
+		s = fmt.Sprintf("%s (fixable, replace %s with %s)", s, i.fix.old, i.fix.new)
+	}
+	return s
+}
+
+// Linter checks test scripts against the K8s cluster they run in.
+type Linter struct {
+	Namespace            string
+	MaxWorkerArrivalRate int
+	ctl                  *kube.Client
+	services             map[string]*corev1.Service
+}
+
+// NewLinter returns a Linter resolving in-cluster targets to Services of a namespace.
+func NewLinter(ns string, maxWorkerArrivalRate int, ctl *kube.Client) *Linter {
+	return &Linter{
+		Namespace:            ns,
+		MaxWorkerArrivalRate: maxWorkerArrivalRate,
+		ctl:                  ctl,
+		services:             map[string]*corev1.Service{},
+	}
+}
+
+// linting collects the LintIssues of a test script file.
+type linting struct {
+	*Linter
+	ctx    context.Context
+	path   string
+	issues []LintIssue
+}
+
+// Lint checks a test script file, beyond schema validation, for:
+// - in-cluster target hostnames not resolving to a Service, or using ports the Service does not expose
+// - arrival rates unrealistic for a single worker
+// - plugins and engines not shipped in the WorkerImage
+func (l *Linter) Lint(ctx context.Context, path string) ([]LintIssue, error) {
+	lt := &linting{Linter: l, ctx: ctx, path: path}
+
+	err := ValidateTestScript(path)
+	var invalid ValidationErrors
+	if err != nil && !errors.As(err, &invalid) {
+		return nil, err
+	}
+	for _, e := range invalid {
+		lt.issues = append(lt.issues, LintIssue{Path: path, Line: e.Line, Column: e.Column, Rule: LintRuleSchema, Message: e.Message})
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		// syntax errors are reported as schema issues
+		return lt.issues, nil
+	}
+	root := doc.Content[0]
+	config := mappingValue(root, "config")
+
+	if err := lt.lintTarget(mappingValue(config, "target")); err != nil {
+		return nil, err
+	}
+	lt.lintPhases(mappingValue(config, "phases"))
+	lt.lintPlugins(mappingValue(config, "plugins"))
+	lt.lintEngines(mappingValue(config, "engines"))
+
+	environments := mappingValue(config, "environments")
+	if environments != nil && environments.Kind == yaml3.MappingNode {
+		for i := 1; i < len(environments.Content); i += 2 {
+			env := environments.Content[i]
+			if err := lt.lintTarget(mappingValue(env, "target")); err != nil {
+				return nil, err
+			}
+			lt.lintPhases(mappingValue(env, "phases"))
+			lt.lintPlugins(mappingValue(env, "plugins"))
+		}
+	}
+
+	for _, section := range []string{"before", "after"} {
+		if err := lt.lintFlow(mappingValue(mappingValue(root, section), "flow")); err != nil {
+			return nil, err
+		}
+	}
+
+	if scenarios := mappingValue(root, "scenarios"); scenarios != nil {
+		for _, scenario := range scenarios.Content {
+			if err := lt.lintFlow(mappingValue(scenario, "flow")); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return lt.issues, nil
+}
+
+// lintFlow checks the target of every absolute request url in a flow, including requests in loops.
+func (lt *linting) lintFlow(flow *yaml3.Node) error {
+	if flow == nil || flow.Kind != yaml3.SequenceNode {
+		return nil
+	}
+
+	for _, step := range flow.Content {
+		if err := lt.lintFlow(mappingValue(step, "loop")); err != nil {
+			return err
+		}
+
+		if _, req := request(step); req != nil {
+			if err := lt.lintUrl(mappingValue(req, "url")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lintTarget checks in-cluster targets resolve to a Service exposing the target port.
+// Targets without a scheme are comma separated host:port lists, e.g. gRPC or Kafka bootstrap servers.
+// Templated and relative targets are skipped.
+func (lt *linting) lintTarget(node *yaml3.Node) error {
+	if !nonEmptyScalar(node) || strings.Contains(node.Value, "{{") || strings.HasPrefix(node.Value, "/") {
+		return nil
+	}
+
+	if !strings.Contains(node.Value, "://") {
+		for _, hostPort := range strings.Split(node.Value, ",") {
+			if err := lt.lintHostPort(node, "", strings.TrimSpace(hostPort)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return lt.lintUrl(node)
+}
+
+// lintUrl checks the target of an absolute url, e.g. a request url.
+// Templated urls and relative urls, resolved against the test script target, are skipped.
+func (lt *linting) lintUrl(node *yaml3.Node) error {
+	if !nonEmptyScalar(node) || strings.Contains(node.Value, "{{") {
+		return nil
+	}
+
+	u, err := url.Parse(node.Value)
+	if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+		return nil
+	}
+	return lt.lintHostPort(node, u.Scheme, u.Host)
+}
+
+// lintHostPort checks an in-cluster host resolves to a Service exposing the port.
+// A port the Service does not expose is fixed when it is a target port of the Service, or the Service has a single port.
+func (lt *linting) lintHostPort(node *yaml3.Node, scheme, hostPort string) error {
+	host, port := hostPort, defaultPort(scheme)
+	if h, p, err := net.SplitHostPort(hostPort); err == nil {
+		host = h
+		port, _ = strconv.Atoi(p)
+	}
+
+	name, ns, ok := kube.ClusterServiceHost(host, lt.Namespace)
+	if !ok {
+		return nil
+	}
+
+	svc, err := lt.service(name, ns)
+	if err != nil {
+		return err
+	}
+	if svc == nil {
+		lt.add(node, LintRuleTarget, fmt.Sprintf("host %q does not resolve to a Service in namespace %q", host, ns), nil)
+		return nil
+	}
+
+	if svc.Spec.Type == corev1.ServiceTypeExternalName || port == 0 {
+		return nil
+	}
+
+	var exposed []string
+	var fixPort int32
+	for _, p := range svc.Spec.Ports {
+		if int(p.Port) == port {
+			return nil
+		}
+		if p.TargetPort.IntValue() == port {
+			fixPort = p.Port
+		}
+		exposed = append(exposed, strconv.Itoa(int(p.Port)))
+	}
+	if fixPort == 0 && len(svc.Spec.Ports) == 1 {
+		fixPort = svc.Spec.Ports[0].Port
+	}
+
+	var fix *lintFix
+	if fixPort != 0 {
+		fixed := net.JoinHostPort(host, strconv.Itoa(int(fixPort)))
+		if len(scheme) > 0 {
+			fixed = fmt.Sprintf("%s://%s", scheme, fixed)
+			hostPort = fmt.Sprintf("%s://%s", scheme, hostPort)
+		}
+		fix = &lintFix{old: hostPort, new: fixed}
+	}
+
+	lt.add(node, LintRulePort, fmt.Sprintf("Service %q does not expose port %d, exposed ports are %s", name, port, strings.Join(exposed, ", ")), fix)
+	return nil
+}
+
+// lintPhases checks phase arrival rates are realistic for a single worker.
+func (lt *linting) lintPhases(phases *yaml3.Node) {
+	if phases == nil || phases.Kind != yaml3.SequenceNode || lt.MaxWorkerArrivalRate <= 0 {
+		return
+	}
+
+	for _, phase := range phases.Content {
+		for _, key := range []string{"arrivalRate", "rampTo"} {
+			node := mappingValue(phase, key)
+			if node == nil || node.ShortTag() != "!!int" {
+				continue
+			}
+
+			rate, err := strconv.Atoi(node.Value)
+			if err != nil || rate <= lt.MaxWorkerArrivalRate {
+				continue
+			}
+
+			workers := (rate + lt.MaxWorkerArrivalRate - 1) / lt.MaxWorkerArrivalRate
+			lt.add(node, LintRuleArrivalRate, fmt.Sprintf(
+				"%s %d is unrealistic for one worker sustaining about %d/s, split the load using generate --count %d",
+				key, rate, lt.MaxWorkerArrivalRate, workers,
+			), nil)
+		}
+	}
+}
+
+// lintPlugins checks plugins are shipped in the WorkerImage.
+func (lt *linting) lintPlugins(plugins *yaml3.Node) {
+	for _, key := range mappingKeyNodes(plugins) {
+		if p := (NpmPackage{Name: key.Value, Kind: "plugin"}); !p.Shipped() {
+			lt.add(key, LintRulePlugin, fmt.Sprintf("plugin %q is not shipped in the %s image, it requires %s, installed by generate", p.Name, WorkerImage, p.Package()), nil)
+		}
+	}
+}
+
+// lintEngines checks engines are shipped in the WorkerImage.
+func (lt *linting) lintEngines(engines *yaml3.Node) {
+	for _, key := range mappingKeyNodes(engines) {
+		if p := (NpmPackage{Name: key.Value, Kind: "engine"}); !p.Shipped() {
+			lt.add(key, LintRuleEngine, fmt.Sprintf("engine %q is not shipped in the %s image, it requires %s, installed by generate", p.Name, WorkerImage, p.Package()), nil)
+		}
+	}
+}
+
+// service returns a Service by name and namespace, querying the K8s cluster once per Service.
+func (lt *linting) service(name, ns string) (*corev1.Service, error) {
+	key := ns + "/" + name
+	if svc, found := lt.services[key]; found {
+		return svc, nil
+	}
+
+	svc, err := kube.GetService(lt.ctx, name, ns, lt.ctl)
+	if err != nil {
+		return nil, err
+	}
+	lt.services[key] = svc
+	return svc, nil
+}
+
+// add adds a LintIssue at a node's position.
+func (lt *linting) add(at *yaml3.Node, rule, msg string, fix *lintFix) {
+	lt.issues = append(lt.issues, LintIssue{
+		Path:    lt.path,
+		Line:    at.Line,
+		Column:  at.Column,
+		Rule:    rule,
+		Message: msg,
+		fix:     fix,
+	})
+}
+
+// FixTestScript applies the fixes of fixable LintIssues to a test script file, keeping its formatting.
+// Returns the number of fixed issues.
+func FixTestScript(path string, issues []LintIssue) (int, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return 0, err
+	}
+
+	var edits []lineEdit
+	for _, issue := range issues {
+		if issue.Fixable() {
+			edits = append(edits, lineEdit{line: issue.Line, old: issue.fix.old, new: issue.fix.new})
+		}
+	}
+
+	fixedData, fixed := applyLineEdits(data, edits)
+	if fixed == 0 {
+		return 0, nil
+	}
+	return fixed, os.WriteFile(path, fixedData, 0644)
+}
+
+// defaultPort returns the default port of a url scheme, or 0 when unknown.
+func defaultPort(scheme string) int {
+	switch scheme {
+	case "http", "ws":
+		return 80
+	case "https", "wss":
+		return 443
+	}
+	return 0
+}
+
+// mappingKeyNodes returns the key nodes of a mapping node.
+func mappingKeyNodes(node *yaml3.Node) []*yaml3.Node {
+	var keys []*yaml3.Node
+	if node == nil || node.Kind != yaml3.MappingNode {
+		return keys
+	}
+
+	for i := 0; i < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i])
+	}
+	return keys
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// lintServices a Linter without cluster access, knowing the shop namespace's orders Service.
+// Looking up any other Service fails the test with a nil client panic.
+func lintServices() *Linter {
+	l := NewLinter("shop", 250, nil)
+	l.services["shop/orders"] = &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "orders", Namespace: "shop"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}}},
+	}
+	l.services["shop/payments"] = nil
+	return l
+}
+
+func TestLintTargets(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		rules  []string
+	}{
+		{
+			name: "relative urls",
+			script: `
+config:
+  target: http://orders:80
+  phases: [{duration: 1, arrivalRate: 1}]
+scenarios:
+  - flow:
+      - get: {url: /orders}
+      - post: {url: "/orders?page=1"}
+      - get: {url: orders/1}
+      - loop:
+          - get: {url: "/orders/{{ $loopElement }}"}
+        over: [1, 2]
+`,
+		},
+		{
+			name: "absolute urls",
+			script: `
+config:
+  target: http://orders
+  phases: [{duration: 1, arrivalRate: 1}]
+scenarios:
+  - flow:
+      - get: {url: "http://orders:8080/"}
+      - get: {url: "http://payments/"}
+      - get: {url: "https://api.example.com/"}
+`,
+			rules: []string{LintRulePort, LintRuleTarget},
+		},
+		{
+			name: "host port lists",
+			script: `
+config:
+  target: orders:80,payments:9092
+  phases: [{duration: 1, arrivalRate: 1}]
+scenarios:
+  - flow:
+      - get: {url: /}
+`,
+			rules: []string{LintRuleTarget},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test-script.yaml")
+			if err := os.WriteFile(path, []byte(tt.script), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			issues, err := lintServices().Lint(context.TODO(), path)
+			if err != nil {
+				t.Fatalf("Lint() error = %v", err)
+			}
+			if len(issues) != len(tt.rules) {
+				t.Fatalf("Lint() = %v, want %d issues", issues, len(tt.rules))
+			}
+			for i, issue := range issues {
+				if issue.Rule != tt.rules[i] {
+					t.Errorf("issue %d = %s, want rule %s", i, issue, tt.rules[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLintPlugins(t *testing.T) {
+	script := `
+config:
+  target: http://orders
+  phases: [{duration: 1, arrivalRate: 1}]
+  plugins:
+    expect: {}
+    publish-metrics: [{type: prometheus, pushgateway: http://pushgateway:9091}]
+  engines:
+    playwright: {}
+    kafka: {}
+  environments:
+    functional:
+      plugins:
+        ensure: {}
+scenarios:
+  - flow:
+      - get: {url: /}
+`
+	path := filepath.Join(t.TempDir(), "test-script.yaml")
+	if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := lintServices().Lint(context.TODO(), path)
+	if err != nil {
+		t.Fatalf("Lint() error = %v", err)
+	}
+
+	want := []string{
+		"plugin \"publish-metrics\"",
+		"engine \"kafka\"",
+		"plugin \"ensure\"",
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("Lint() = %v, want %d issues", issues, len(want))
+	}
+	for i, issue := range issues {
+		if !strings.HasPrefix(issue.Message, want[i]) {
+			t.Errorf("issue %d = %s, want %s", i, issue, want[i])
+		}
+	}
+}
+
+func TestLintScaffoldedTestScript(t *testing.T) {
+	data, err := ordersTestScript("/healthz").MarshalWithIndent(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "test-script.yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := lintServices().Lint(context.TODO(), path)
+	if err != nil {
+		t.Fatalf("Lint() error = %v", err)
+	}
+	if len(issues) > 0 {
+		t.Errorf("Lint() = %v, want no issues for a scaffolded test script", issues)
+	}
+}
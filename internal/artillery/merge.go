@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import "fmt"
+
+// NamedTestScript a test script named after its scaffolded target, e.g. a K8s Service.
+type NamedTestScript struct {
+	Name   string
+	Script *TestScript
+}
+
+// HasNamedTestScript returns whether a list of named test scripts includes a name.
+func HasNamedTestScript(scripts []NamedTestScript, name string) bool {
+	for _, script := range scripts {
+		if script.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeTestScripts merges named test scripts into a single test script, exercising all their targets.
+// Every test script becomes a named scenario, weighted using its name's weight, defaulting to Artillery's weight of 1.
+// Every test script also gets its own environment targeting it, using the test script's functional phases.
+// The first test script provides the default target, the first HTTP test script provides the default phases,
+// as streaming and Kafka test scripts use phases sized for their own protocols, e.g. publish rates.
+// Scenarios of engines other than HTTP use the default target rather than full urls,
+// so test scripts with such scenarios targeting another host cannot be merged.
+func MergeTestScripts(scripts []NamedTestScript, weights map[string]int) (*TestScript, error) {
+	merged := &TestScript{
+		Config: Config{
+			Environments: map[string]Environment{},
+		},
+	}
+
+	if err := checkMergeable(scripts); err != nil {
+		return nil, err
+	}
+
+	for _, named := range scripts {
+		ts := named.Script
+		if len(merged.Config.Target) == 0 {
+			merged.Config.Target = ts.Config.Target
+		}
+
+
+		for name, env := range ts.Config.Environments {
+			if _, found := merged.Config.Environments[name]; !found {
+				merged.Config.Environments[name] = env
+			}
+		}
+
+		own := Environment{
+			Target:  ts.Config.Target,
+			Phases:  ts.Config.Phases,
+			Plugins: map[string]interface{}{},
+		}
+		if functional, found := ts.Config.Environments["functional"]; found {
+			own.Phases = functional.Phases
+			own.Plugins = functional.Plugins
+		}
+		merged.Config.Environments[named.Name] = own
+
+		merged.Config.Variables = mergeMaps(merged.Config.Variables, ts.Config.Variables)
+		merged.Config.Engines = mergeMaps(merged.Config.Engines, ts.Config.Engines)
+
+		for _, scenario := range ts.Scenarios {
+			if len(scenario.Name) == 0 {
+				scenario.Name = named.Name
+			}
+			if weight, found := weights[named.Name]; found {
+				scenario.Weight = &weight
+			}
+			merged.Scenarios = append(merged.Scenarios, scenario)
+		}
+	}
+
+	merged.Config.Phases = mergedPhases(scripts)
+
+	return merged, nil
+}
+
+// mergedPhases returns the phases of the first HTTP test script having any,
+// falling back to the first test script having any when none is HTTP.
+func mergedPhases(scripts []NamedTestScript) []Phase {
+	var phases []Phase
+	for _, named := range scripts {
+		if len(named.Script.Config.Phases) == 0 {
+			continue
+		}
+		if isHTTPTestScript(named.Script) {
+			return named.Script.Config.Phases
+		}
+		if len(phases) == 0 {
+			phases = named.Script.Config.Phases
+		}
+	}
+	return phases
+}
+
+// isHTTPTestScript returns whether all scenarios of a test script use the HTTP engine.
+func isHTTPTestScript(ts *TestScript) bool {
+	for _, scenario := range ts.Scenarios {
+		if len(scenario.Engine) > 0 && scenario.Engine != "http" {
+			return false
+		}
+	}
+	return true
+}
+
+// checkMergeable checks test scripts have unique names not colliding with their environments,
+// and that scenarios of engines other than HTTP target the first test script's target.
+func checkMergeable(scripts []NamedTestScript) error {
+	names := map[string]bool{}
+	for _, named := range scripts {
+		if names[named.Name] {
+			return fmt.Errorf("cannot merge test scripts, %q is scaffolded twice", named.Name)
+		}
+		names[named.Name] = true
+	}
+
+	for i, named := range scripts {
+		for env := range named.Script.Config.Environments {
+			if names[env] {
+				return fmt.Errorf("cannot merge test scripts, %q is both a scaffolded name and an environment of %q", env, named.Name)
+			}
+		}
+
+		if i == 0 || named.Script.Config.Target == scripts[0].Script.Config.Target {
+			continue
+		}
+		for _, scenario := range named.Script.Scenarios {
+			if len(scenario.Engine) > 0 && scenario.Engine != "http" {
+				return fmt.Errorf("cannot merge %q, its %s scenarios target %s rather than %s, scaffold it without --single-file",
+					named.Name, scenario.Engine, named.Script.Config.Target, scripts[0].Script.Config.Target)
+			}
+		}
+	}
+	return nil
+}
+
+// mergeMaps adds any missing keys from src to dst, creating dst when required.
+func mergeMaps(dst, src map[string]interface{}) map[string]interface{} {
+	if len(src) == 0 {
+		return dst
+	}
+
+	if dst == nil {
+		dst = map[string]interface{}{}
+	}
+
+	for k, v := range src {
+		if _, found := dst[k]; !found {
+			dst[k] = v
+		}
+	}
+	return dst
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mergeScript returns a named test script targeting a host, with a scenario using an engine.
+func mergeScript(name, target, engine string) NamedTestScript {
+	return NamedTestScript{
+		Name: name,
+		Script: &TestScript{
+			Config: Config{
+				Target: target,
+				Environments: map[string]Environment{
+					"functional": {Phases: []Phase{{Duration: FromInt(1), ArrivalCount: FromInt(1)}}},
+				},
+			},
+			Scenarios: []Scenario{{Engine: engine}},
+		},
+	}
+}
+
+func TestMergeTestScripts(t *testing.T) {
+	tests := []struct {
+		name    string
+		scripts []NamedTestScript
+		wantErr string
+	}{
+		{
+			name: "http scenarios",
+			scripts: []NamedTestScript{
+				mergeScript("orders", "http://orders:80/", ""),
+				mergeScript("payments", "http://payments:80/", "http"),
+			},
+		},
+		{
+			name: "streaming scenario of the first script",
+			scripts: []NamedTestScript{
+				mergeScript("chat", "ws://chat:80", "ws"),
+				mergeScript("orders", "http://orders:80/", ""),
+			},
+		},
+		{
+			name: "streaming scenario on another host",
+			scripts: []NamedTestScript{
+				mergeScript("orders", "http://orders:80/", ""),
+				mergeScript("chat", "ws://chat:80", "ws"),
+			},
+			wantErr: `cannot merge "chat", its ws scenarios target ws://chat:80`,
+		},
+		{
+			name: "environment name",
+			scripts: []NamedTestScript{
+				mergeScript("functional", "http://functional:80/", ""),
+			},
+			wantErr: `"functional" is both a scaffolded name and an environment`,
+		},
+		{
+			name: "duplicate name",
+			scripts: []NamedTestScript{
+				mergeScript("orders", "http://orders:80/", ""),
+				mergeScript("orders", "http://orders:80/", ""),
+			},
+			wantErr: `"orders" is scaffolded twice`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := MergeTestScripts(tt.scripts, map[string]int{})
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("MergeTestScripts() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergeTestScripts() error = %v", err)
+			}
+
+			if merged.Config.Target != tt.scripts[0].Script.Config.Target {
+				t.Errorf("target = %q, want %q", merged.Config.Target, tt.scripts[0].Script.Config.Target)
+			}
+			for _, named := range tt.scripts {
+				if env := merged.Config.Environments[named.Name]; env.Target != named.Script.Config.Target {
+					t.Errorf("environment %q target = %q, want %q", named.Name, env.Target, named.Script.Config.Target)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeTestScriptsPhases(t *testing.T) {
+	publish := []Phase{{Duration: FromInt(60), ArrivalRate: FromInt(500)}}
+	browse := []Phase{{Duration: FromInt(60), ArrivalRate: FromInt(5)}}
+
+	withPhases := func(named NamedTestScript, phases []Phase) NamedTestScript {
+		named.Script.Config.Phases = phases
+		return named
+	}
+
+	tests := []struct {
+		name    string
+		scripts []NamedTestScript
+		want    []Phase
+	}{
+		{
+			name: "http script after a kafka script",
+			scripts: []NamedTestScript{
+				withPhases(mergeScript("events", "kafka:9092", "kafka"), publish),
+				withPhases(mergeScript("orders", "kafka:9092", ""), browse),
+			},
+			want: browse,
+		},
+		{
+			name: "first http script",
+			scripts: []NamedTestScript{
+				withPhases(mergeScript("orders", "http://orders:80/", ""), browse),
+				withPhases(mergeScript("payments", "http://payments:80/", "http"), publish),
+			},
+			want: browse,
+		},
+		{
+			name: "no http script",
+			scripts: []NamedTestScript{
+				mergeScript("chat", "ws://chat:80", "ws"),
+				withPhases(mergeScript("events", "ws://chat:80", "socketio"), publish),
+			},
+			want: publish,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := MergeTestScripts(tt.scripts, map[string]int{})
+			if err != nil {
+				t.Fatalf("MergeTestScripts() error = %v", err)
+			}
+			if !reflect.DeepEqual(merged.Config.Phases, tt.want) {
+				t.Errorf("phases = %v, want %v", merged.Config.Phases, tt.want)
+			}
+		})
+	}
+}
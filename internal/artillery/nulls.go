@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"reflect"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// nulls the known keys of a parsed test script mapping explicitly set to null, e.g. json: null.
+// Unknown keys set to null are kept in Extra, known keys are kept here, so both marshal back to null.
+type nulls struct {
+	keys []string
+}
+
+// nullKeys returns the null keys of a test script mapping, promoted to the types embedding nulls.
+func (n *nulls) nullKeys() *nulls {
+	return n
+}
+
+// nullable a test script type keeping its null keys.
+type nullable interface {
+	nullKeys() *nulls
+}
+
+// readNulls records the known keys set to null in a parsed test script document.
+func readNulls(doc *yaml3.Node, v interface{}) {
+	walkNulls(doc, reflect.ValueOf(v), func(mapping *yaml3.Node, fields map[string]int, n *nulls) {
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			key, value := mapping.Content[i], mapping.Content[i+1]
+			if _, known := fields[key.Value]; known && value.ShortTag() == "!!null" {
+				n.keys = append(n.keys, key.Value)
+			}
+		}
+	})
+}
+
+// writeNulls adds the null keys recorded by readNulls to a marshaled test script document,
+// unless a key was set since.
+func writeNulls(doc *yaml3.Node, v interface{}) {
+	walkNulls(doc, reflect.ValueOf(v), func(mapping *yaml3.Node, _ map[string]int, n *nulls) {
+		for _, key := range n.keys {
+			if mappingValue(mapping, key) == nil {
+				mapping.Content = append(mapping.Content,
+					&yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: key},
+					&yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!null", Value: "null"},
+				)
+			}
+		}
+	})
+}
+
+// walkNulls walks a test script document along with its parsed value,
+// calling visit for every mapping parsed into a type keeping its null keys.
+func walkNulls(node *yaml3.Node, v reflect.Value, visit func(*yaml3.Node, map[string]int, *nulls)) {
+	for node.Kind == yaml3.DocumentNode || node.Kind == yaml3.AliasNode {
+		if node.Kind == yaml3.AliasNode {
+			node = node.Alias
+		} else if len(node.Content) > 0 {
+			node = node.Content[0]
+		} else {
+			return
+		}
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		// Payloads and Captures read a single mapping or a list into Items
+		if items := v.FieldByName("Items"); items.IsValid() && items.Kind() == reflect.Slice {
+			if node.Kind == yaml3.MappingNode && items.Len() > 0 {
+				walkNulls(node, items.Index(0), visit)
+			} else {
+				walkNulls(node, items, visit)
+			}
+			return
+		}
+
+		if node.Kind != yaml3.MappingNode {
+			return
+		}
+		fields := yamlFields(v.Type())
+		if v.CanAddr() {
+			if n, ok := v.Addr().Interface().(nullable); ok {
+				visit(node, fields, n.nullKeys())
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if field, known := fields[node.Content[i].Value]; known {
+				walkNulls(node.Content[i+1], v.Field(field), visit)
+			}
+		}
+
+	case reflect.Slice:
+		if node.Kind != yaml3.SequenceNode {
+			return
+		}
+		for i := 0; i < len(node.Content) && i < v.Len(); i++ {
+			walkNulls(node.Content[i], v.Index(i), visit)
+		}
+
+	case reflect.Map:
+		// free form values, e.g. plugin settings, keep their nulls
+		if node.Kind != yaml3.MappingNode || v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() == reflect.Interface {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := reflect.ValueOf(node.Content[i].Value).Convert(v.Type().Key())
+			value := v.MapIndex(key)
+			if !value.IsValid() {
+				continue
+			}
+			// map values are not addressable, walk a copy and store it
+			copied := reflect.New(value.Type()).Elem()
+			copied.Set(value)
+			walkNulls(node.Content[i+1], copied, visit)
+			v.SetMapIndex(key, copied)
+		}
+	}
+}
+
+// yamlFields returns the field indexes of a struct type by YAML key, inline fields excluded.
+func yamlFields(t reflect.Type) map[string]int {
+	fields := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.PkgPath) > 0 {
+			continue
+		}
+		if key := strings.Split(f.Tag.Get("yaml"), ",")[0]; len(key) > 0 && key != "-" {
+			fields[key] = i
+		}
+	}
+	return fields
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ImagePlugins the plugins shipped in the WorkerImage, e.g. expect used by scaffolded functional tests.
+// Other plugins, e.g. publish-metrics, metrics-by-endpoint and ensure, are installed into test workers.
+var ImagePlugins = map[string]bool{
+	"apdex":  true,
+	"expect": true,
+}
+
+// ImageEngines the engines shipped in the WorkerImage.
+var ImageEngines = map[string]bool{
+	"http":       true,
+	"ws":         true,
+	"socketio":   true,
+	"playwright": true,
+}
+
+// NpmPackage an Artillery plugin or engine package installed into test workers.
+type NpmPackage struct {
+	// Name the plugin or engine name used by the test script, e.g. publish-metrics.
+	Name string
+	// Kind either plugin or engine.
+	Kind    string
+	Version string
+}
+
+// Package returns the npm package name, e.g. artillery-plugin-publish-metrics.
+func (p NpmPackage) Package() string {
+	return fmt.Sprintf("artillery-%s-%s", p.Kind, p.Name)
+}
+
+// String returns the npm install spec, pinned to a version when set, e.g. artillery-plugin-publish-metrics@2.1.0.
+func (p NpmPackage) String() string {
+	if len(p.Version) > 0 {
+		return p.Package() + "@" + p.Version
+	}
+	return p.Package()
+}
+
+// RequiredPackages returns the plugins and engines a bundled test script and config file use,
+// that are not shipped in the WorkerImage. Versions pin packages by plugin or engine name.
+// Images other than the WorkerImage are expected to be built from it, shipping the same plugins and engines.
+func (b *Bundle) RequiredPackages(versions map[string]string) ([]NpmPackage, error) {
+	used := map[string]NpmPackage{}
+	for _, name := range []string{b.Script, b.Config} {
+		if len(name) == 0 {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Clean(filepath.Join(b.Dir, name)))
+		if err != nil {
+			return nil, err
+		}
+
+		var doc yaml3.Node
+		if err := yaml3.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		config := mappingValue(&doc, "config")
+		configs := []*yaml3.Node{config}
+		environments := mappingValue(config, "environments")
+		if environments != nil && environments.Kind == yaml3.MappingNode {
+			for i := 1; i < len(environments.Content); i += 2 {
+				configs = append(configs, environments.Content[i])
+			}
+		}
+
+		for _, c := range configs {
+			for _, plugin := range mappingKeys(mappingValue(c, "plugins")) {
+				if p := (NpmPackage{Name: plugin, Kind: "plugin"}); !p.Shipped() {
+					used[plugin] = p
+				}
+			}
+		}
+		for _, engine := range mappingKeys(mappingValue(config, "engines")) {
+			if p := (NpmPackage{Name: engine, Kind: "engine"}); !p.Shipped() {
+				used[engine] = p
+			}
+		}
+	}
+
+	for name, version := range versions {
+		p, found := used[name]
+		if !found && (ImagePlugins[name] || ImageEngines[name]) {
+			return nil, fmt.Errorf("cannot pin %s to %s, it is shipped in the %s image, which always uses its own version", name, version, WorkerImage)
+		}
+		if !found {
+			return nil, fmt.Errorf("cannot pin %s to %s, it is not a plugin or engine of the test script", name, version)
+		}
+		p.Version = version
+		used[name] = p
+	}
+
+	var packages []NpmPackage
+	for _, p := range used {
+		packages = append(packages, p)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Package() < packages[j].Package() })
+	return packages, nil
+}
+
+// Shipped checks whether a plugin or engine is shipped in the WorkerImage.
+func (p NpmPackage) Shipped() bool {
+	if p.Kind == "engine" {
+		return ImageEngines[p.Name]
+	}
+	return ImagePlugins[p.Name]
+}
+
+// WithPackages installs plugin and engine packages into a volume shared with test workers, using an init container.
+// The init container runs the worker image, and test workers find the packages through NODE_PATH.
+func (j *Job) WithPackages(packages []NpmPackage) *Job {
+	if len(packages) == 0 {
+		return j
+	}
+
+	spec := &j.Spec.Template.Spec
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name:         JobPluginsVol,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+
+	var specs []string
+	for _, p := range packages {
+		specs = append(specs, p.String())
+	}
+
+	mount := corev1.VolumeMount{Name: JobPluginsVol, MountPath: JobPluginsMountPath}
+	worker := &spec.Containers[0]
+	// installed before other init containers, e.g. the start barrier, which must run last
+	spec.InitContainers = append([]corev1.Container{
+		{
+			Name:            "install-plugins",
+			Image:           worker.Image,
+			ImagePullPolicy: worker.ImagePullPolicy,
+			Command: append(
+				[]string{"npm", "install", "--prefix", JobPluginsMountPath, "--no-save", "--no-audit", "--no-fund"},
+				specs...,
+			),
+			VolumeMounts: []corev1.VolumeMount{mount},
+		},
+	}, spec.InitContainers...)
+
+	worker.VolumeMounts = append(worker.VolumeMounts, mount)
+	worker.Env = append(worker.Env, corev1.EnvVar{
+		Name:  "NODE_PATH",
+		Value: strings.Join([]string{JobPluginsMountPath, "node_modules"}, "/"),
+	})
+	return j
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/artilleryio/kubectl-artillery/internal/telemetry"
+)
+
+const pluginsScript = `config:
+  target: http://orders
+  phases: [{duration: 60, arrivalRate: 10}]
+  plugins:
+    publish-metrics: [{type: prometheus, pushgateway: http://pushgateway:9091}]
+    metrics-by-endpoint: {}
+    ensure: {}
+  engines:
+    kafka: {}
+    playwright: {}
+  environments:
+    staging:
+      plugins:
+        expect: {}
+scenarios:
+  - flow:
+      - get: {url: /orders}
+`
+
+func TestRequiredPackages(t *testing.T) {
+	b := bundleFiles(t, pluginsScript, nil)
+
+	packages, err := b.RequiredPackages(map[string]string{"publish-metrics": "2.1.0", "kafka": "0.1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, p := range packages {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"artillery-engine-kafka@0.1.0",
+		"artillery-plugin-ensure",
+		"artillery-plugin-metrics-by-endpoint",
+		"artillery-plugin-publish-metrics@2.1.0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RequiredPackages() = %v, want %v", got, want)
+	}
+
+	if _, err := b.RequiredPackages(map[string]string{"slack": "1.2.0"}); err == nil || !strings.Contains(err.Error(), "not a plugin or engine") {
+		t.Errorf("pinning an unused plugin error = %v", err)
+	}
+	if _, err := b.RequiredPackages(map[string]string{"expect": "2.0.0"}); err == nil || !strings.Contains(err.Error(), "is shipped in") {
+		t.Errorf("pinning a shipped plugin error = %v", err)
+	}
+}
+
+func TestWithPackages(t *testing.T) {
+	job := NewTestJob("orders", "shop", "orders-test-script", "test-script.yaml", 1, telemetry.Config{}).
+		WithPackages([]NpmPackage{{Name: "ensure", Kind: "plugin", Version: "1.0.0"}})
+
+	spec := job.Spec.Template.Spec
+	if len(spec.InitContainers) != 1 {
+		t.Fatalf("got %d init containers, want 1", len(spec.InitContainers))
+	}
+	install := spec.InitContainers[0]
+	if install.Image != spec.Containers[0].Image {
+		t.Errorf("install-plugins image = %s, want the worker image %s", install.Image, spec.Containers[0].Image)
+	}
+	if command := install.Command; command[len(command)-1] != "artillery-plugin-ensure@1.0.0" {
+		t.Errorf("install-plugins command = %v", command)
+	}
+
+	var nodePath string
+	for _, env := range spec.Containers[0].Env {
+		if env.Name == "NODE_PATH" {
+			nodePath = env.Value
+		}
+	}
+	if nodePath != JobPluginsMountPath+"/node_modules" {
+		t.Errorf("NODE_PATH = %q", nodePath)
+	}
+}
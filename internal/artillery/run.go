@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// RunOptions artillery run options passed to test workers, see: artillery run --help.
+type RunOptions struct {
+	// Environment the test script environment to run, see: artillery run --environment.
+	Environment string
+	// Target overrides the test script target, see: artillery run --target.
+	Target string
+	// Overrides a JSON object overriding parts of the test script, see: artillery run --overrides.
+	Overrides string
+	// Variables a JSON object of variables made available to scenarios, see: artillery run --variables.
+	Variables string
+}
+
+// Validate checks the target is a URL, and overrides and variables are JSON objects.
+func (o RunOptions) Validate() error {
+	if len(o.Target) > 0 {
+		u, err := url.Parse(o.Target)
+		if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+			return fmt.Errorf("target %q must be a URL, e.g. http://my-service:8080", o.Target)
+		}
+	}
+
+	if _, err := runJSONObject(o.Overrides); err != nil {
+		return fmt.Errorf("overrides must be a JSON object: %w", err)
+	}
+
+	if _, err := runJSONObject(o.Variables); err != nil {
+		return fmt.Errorf("variables must be a JSON object: %w", err)
+	}
+	return nil
+}
+
+// WithWorkerVariables adds the WORKER_INDEX and WORKER_COUNT variables to Variables,
+// expanded by Kubernetes from the test worker's environment variables.
+// Variables must be valid, see: Validate.
+func (o RunOptions) WithWorkerVariables() RunOptions {
+	variables, _ := runJSONObject(o.Variables)
+	if variables == nil {
+		variables = map[string]interface{}{}
+	}
+	variables["WORKER_INDEX"] = "$(WORKER_INDEX)"
+	variables["WORKER_COUNT"] = "$(WORKER_COUNT)"
+
+	data, _ := json.Marshal(variables)
+	o.Variables = string(data)
+	return o
+}
+
+// target returns the target the test runs against, set by Target or config.target in Overrides.
+func (o RunOptions) target() string {
+	if len(o.Target) > 0 {
+		return o.Target
+	}
+
+	overrides, _ := runJSONObject(o.Overrides)
+	config, _ := overrides["config"].(map[string]interface{})
+	target, _ := config["target"].(string)
+	return target
+}
+
+// args returns the artillery run arguments for RunOptions.
+func (o RunOptions) args() []string {
+	var args []string
+	if len(o.Environment) > 0 {
+		args = append(args, "--environment", o.Environment)
+	}
+	if len(o.Target) > 0 {
+		args = append(args, "--target", o.Target)
+	}
+	if len(o.Overrides) > 0 {
+		args = append(args, "--overrides", o.Overrides)
+	}
+	if len(o.Variables) > 0 {
+		args = append(args, "--variables", o.Variables)
+	}
+	return args
+}
+
+// runJSONObject parses an optional JSON object run option.
+func runJSONObject(s string) (map[string]interface{}, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
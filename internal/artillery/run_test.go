@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/artilleryio/kubectl-artillery/internal/telemetry"
+)
+
+func TestRunOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    RunOptions
+		wantErr bool
+	}{
+		{name: "empty"},
+		{
+			name: "all options",
+			opts: RunOptions{
+				Environment: "autoscale",
+				Target:      "http://orders:8080",
+				Overrides:   `{"config":{"phases":[]}}`,
+				Variables:   `{"region":"eu"}`,
+			},
+		},
+		{name: "target without scheme", opts: RunOptions{Target: "orders:8080"}, wantErr: true},
+		{name: "target without host", opts: RunOptions{Target: "http://"}, wantErr: true},
+		{name: "overrides not JSON", opts: RunOptions{Overrides: "config: {}"}, wantErr: true},
+		{name: "overrides not an object", opts: RunOptions{Overrides: "[]"}, wantErr: true},
+		{name: "variables not an object", opts: RunOptions{Variables: `"eu"`}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunOptionsTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		opts RunOptions
+		want string
+	}{
+		{name: "none"},
+		{name: "target", opts: RunOptions{Target: "http://orders:8080"}, want: "http://orders:8080"},
+		{
+			name: "overrides target",
+			opts: RunOptions{Overrides: `{"config":{"target":"http://payments:8080"}}`},
+			want: "http://payments:8080",
+		},
+		{
+			name: "target before overrides",
+			opts: RunOptions{Target: "http://orders:8080", Overrides: `{"config":{"target":"http://payments:8080"}}`},
+			want: "http://orders:8080",
+		},
+		{name: "overrides without target", opts: RunOptions{Overrides: `{"config":{"phases":[]}}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.target(); got != tt.want {
+				t.Errorf("target() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobRunArgs(t *testing.T) {
+	tests := []struct {
+		name         string
+		configFile   string
+		opts         RunOptions
+		workerScript string
+		want         []string
+	}{
+		{
+			name: "test script only",
+			want: []string{"run", "/data/test-script.yaml"},
+		},
+		{
+			name:       "config file",
+			configFile: "config.yaml",
+			want:       []string{"run", "--config", "/data/config.yaml", "/data/test-script.yaml"},
+		},
+		{
+			name:       "config file and run options",
+			configFile: "config.yaml",
+			opts:       RunOptions{Environment: "autoscale", Target: "http://orders:8080", Variables: `{"region":"eu"}`},
+			want: []string{
+				"run", "--config", "/data/config.yaml",
+				"--environment", "autoscale", "--target", "http://orders:8080", "--variables", `{"region":"eu"}`,
+				"/data/test-script.yaml",
+			},
+		},
+		{
+			name:         "worker scripts",
+			configFile:   "config.yaml",
+			opts:         RunOptions{Overrides: `{"config":{}}`},
+			workerScript: "test-script-$(WORKER_INDEX).yaml",
+			want: []string{
+				"run", "--config", "/data/config.yaml", "--overrides", `{"config":{}}`,
+				"/data/test-script-$(WORKER_INDEX).yaml",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := NewTestJob("orders", "shop", "orders-test-script", "test-script.yaml", 2, telemetry.Config{}).
+				WithConfigFile(tt.configFile).
+				WithRunOptions(tt.opts).
+				WithWorkerScripts(tt.workerScript)
+
+			if got := job.Spec.Template.Spec.Containers[0].Args; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sLabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// spreadTopologyKeys the node label test workers are spread across, by --spread value.
+var spreadTopologyKeys = map[string]string{
+	"node": "kubernetes.io/hostname",
+	"zone": "topology.kubernetes.io/zone",
+}
+
+// WorkerScheduling the resources and scheduling constraints of test worker Pods,
+// set by the workers section of the CLI settings file and generate flags.
+type WorkerScheduling struct {
+	Resources         corev1.ResourceRequirements `json:"resources,omitempty"`
+	NodeSelector      map[string]string           `json:"nodeSelector,omitempty"`
+	Tolerations       []corev1.Toleration         `json:"tolerations,omitempty"`
+	PriorityClassName string                      `json:"priorityClassName,omitempty"`
+	Affinity          *corev1.Affinity            `json:"affinity,omitempty"`
+	// Spread spreads test workers across nodes or zones, either node or zone.
+	Spread string `json:"spread,omitempty"`
+}
+
+// Validate checks Spread is either node or zone.
+func (s WorkerScheduling) Validate() error {
+	if _, found := spreadTopologyKeys[s.Spread]; len(s.Spread) > 0 && !found {
+		return fmt.Errorf("unknown spread %q, use node or zone", s.Spread)
+	}
+	return nil
+}
+
+// Override returns WorkerScheduling overridden by the settings set in another WorkerScheduling.
+// Resources and node selectors are overridden by name, tolerations are added.
+func (s WorkerScheduling) Override(o WorkerScheduling) WorkerScheduling {
+	s.Resources.Requests = overrideResources(s.Resources.Requests, o.Resources.Requests)
+	s.Resources.Limits = overrideResources(s.Resources.Limits, o.Resources.Limits)
+
+	if len(o.NodeSelector) > 0 {
+		selector := map[string]string{}
+		for k, v := range s.NodeSelector {
+			selector[k] = v
+		}
+		for k, v := range o.NodeSelector {
+			selector[k] = v
+		}
+		s.NodeSelector = selector
+	}
+
+	s.Tolerations = append(append([]corev1.Toleration{}, s.Tolerations...), o.Tolerations...)
+	if len(o.PriorityClassName) > 0 {
+		s.PriorityClassName = o.PriorityClassName
+	}
+	if o.Affinity != nil {
+		s.Affinity = o.Affinity
+	}
+	if len(o.Spread) > 0 {
+		s.Spread = o.Spread
+	}
+	return s
+}
+
+// ParseWorkerScheduling parses a WorkerScheduling from a settings file section, rejecting unknown settings.
+func ParseWorkerScheduling(section map[string]interface{}) (WorkerScheduling, error) {
+	var s WorkerScheduling
+	if len(section) == 0 {
+		return s, nil
+	}
+
+	data, err := json.Marshal(section)
+	if err != nil {
+		return s, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&s); err != nil {
+		return s, fmt.Errorf("invalid workers settings: %w", err)
+	}
+	return s, s.Validate()
+}
+
+// ParseResourceList parses resource quantities by name, e.g. cpu=500m and memory=512Mi.
+func ParseResourceList(quantities map[string]string) (corev1.ResourceList, error) {
+	if len(quantities) == 0 {
+		return nil, nil
+	}
+
+	list := corev1.ResourceList{}
+	for name, value := range quantities {
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s quantity %q: %w", name, value, err)
+		}
+		list[corev1.ResourceName(name)] = q
+	}
+	return list, nil
+}
+
+// ParseToleration parses a toleration using the kubectl taint syntax, key[=value]:effect.
+// An empty effect tolerates all effects, a missing value tolerates any value.
+func ParseToleration(s string) (corev1.Toleration, error) {
+	sep := strings.LastIndex(s, ":")
+	if sep < 0 {
+		return corev1.Toleration{}, fmt.Errorf("invalid toleration %q, use key[=value]:effect, e.g. dedicated=load:NoSchedule", s)
+	}
+
+	t := corev1.Toleration{
+		Key:      s[:sep],
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffect(s[sep+1:]),
+	}
+	if i := strings.Index(t.Key, "="); i >= 0 {
+		t.Key, t.Value = t.Key[:i], t.Key[i+1:]
+		t.Operator = corev1.TolerationOpEqual
+	}
+
+	switch t.Effect {
+	case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		return corev1.Toleration{}, fmt.Errorf("invalid toleration %q, effect must be NoSchedule, PreferNoSchedule or NoExecute", s)
+	}
+	if len(t.Key) == 0 {
+		return corev1.Toleration{}, fmt.Errorf("invalid toleration %q, key is missing", s)
+	}
+	return t, nil
+}
+
+// nodeSelectorOperators the node selector operator of each label selector operator.
+var nodeSelectorOperators = map[selection.Operator]corev1.NodeSelectorOperator{
+	selection.Equals:       corev1.NodeSelectorOpIn,
+	selection.DoubleEquals: corev1.NodeSelectorOpIn,
+	selection.In:           corev1.NodeSelectorOpIn,
+	selection.NotEquals:    corev1.NodeSelectorOpNotIn,
+	selection.NotIn:        corev1.NodeSelectorOpNotIn,
+	selection.Exists:       corev1.NodeSelectorOpExists,
+	selection.DoesNotExist: corev1.NodeSelectorOpDoesNotExist,
+	selection.GreaterThan:  corev1.NodeSelectorOpGt,
+	selection.LessThan:     corev1.NodeSelectorOpLt,
+}
+
+// ParseAffinity parses the affinity of test workers, either a YAML or JSON file holding a Pod affinity,
+// or a required node affinity using the label selector syntax, e.g. kubernetes.io/arch in (amd64,arm64),pool!=spot.
+func ParseAffinity(s string) (*corev1.Affinity, error) {
+	if info, err := os.Stat(s); err == nil && !info.IsDir() {
+		return readAffinity(s)
+	}
+
+	requirements, err := k8sLabels.ParseToRequirements(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid affinity %q, use a file or node labels, e.g. kubernetes.io/arch in (amd64,arm64): %w", s, err)
+	}
+	if len(requirements) == 0 {
+		return nil, fmt.Errorf("invalid affinity %q, no node labels are required", s)
+	}
+
+	var term corev1.NodeSelectorTerm
+	for _, r := range requirements {
+		expression := corev1.NodeSelectorRequirement{Key: r.Key(), Operator: nodeSelectorOperators[r.Operator()]}
+		if r.Values().Len() > 0 {
+			expression.Values = r.Values().List()
+		}
+		term.MatchExpressions = append(term.MatchExpressions, expression)
+	}
+	return &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{term},
+			},
+		},
+	}, nil
+}
+
+// readAffinity reads a Pod affinity from a YAML or JSON file, rejecting unknown fields.
+func readAffinity(path string) (*corev1.Affinity, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := yaml3.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%s: affinity is empty", path)
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	var affinity corev1.Affinity
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&affinity); err != nil {
+		return nil, fmt.Errorf("%s: invalid affinity: %w", path, err)
+	}
+	return &affinity, nil
+}
+
+// WithScheduling sets the resources of test workers, and constrains the nodes their Pods are scheduled on.
+// Init containers get the worker resources too, so Pods are admitted where a LimitRange or ResourceQuota
+// requires them, a Pod's effective requests are unchanged as init containers run before the worker.
+func (j *Job) WithScheduling(s WorkerScheduling) *Job {
+	spec := &j.Spec.Template.Spec
+	spec.Containers[0].Resources = s.Resources
+	for i := range spec.InitContainers {
+		spec.InitContainers[i].Resources = s.Resources
+	}
+	spec.NodeSelector = s.NodeSelector
+	spec.Tolerations = s.Tolerations
+	spec.PriorityClassName = s.PriorityClassName
+	spec.Affinity = s.Affinity
+
+	if key, found := spreadTopologyKeys[s.Spread]; found {
+		spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{
+			{
+				MaxSkew:           1,
+				TopologyKey:       key,
+				WhenUnsatisfiable: corev1.DoNotSchedule,
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: j.Spec.Template.Labels,
+				},
+			},
+		}
+	}
+	return j
+}
+
+// overrideResources returns resource quantities overridden by name.
+func overrideResources(list, overrides corev1.ResourceList) corev1.ResourceList {
+	if len(overrides) == 0 {
+		return list
+	}
+
+	out := corev1.ResourceList{}
+	for name, q := range list {
+		out[name] = q
+	}
+	for name, q := range overrides {
+		out[name] = q
+	}
+	return out
+}
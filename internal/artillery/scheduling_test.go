@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/artilleryio/kubectl-artillery/internal/telemetry"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseToleration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    corev1.Toleration
+		wantErr bool
+	}{
+		{
+			in:   "dedicated=load:NoSchedule",
+			want: corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "load", Effect: corev1.TaintEffectNoSchedule},
+		},
+		{
+			in:   "dedicated:NoExecute",
+			want: corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		},
+		{
+			in:   "dedicated=load:",
+			want: corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "load"},
+		},
+		{
+			in:   "example.com/gpu=a:b:PreferNoSchedule",
+			want: corev1.Toleration{Key: "example.com/gpu", Operator: corev1.TolerationOpEqual, Value: "a:b", Effect: corev1.TaintEffectPreferNoSchedule},
+		},
+		{in: "dedicated", wantErr: true},
+		{in: "dedicated=load:NoRun", wantErr: true},
+		{in: "=load:NoSchedule", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseToleration(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseToleration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseToleration() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAffinity(t *testing.T) {
+	affinity, err := ParseAffinity("kubernetes.io/arch in (arm64,amd64),pool!=spot,gpu,!preemptible")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []corev1.NodeSelectorRequirement{
+		{Key: "gpu", Operator: corev1.NodeSelectorOpExists},
+		{Key: "kubernetes.io/arch", Operator: corev1.NodeSelectorOpIn, Values: []string{"amd64", "arm64"}},
+		{Key: "pool", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"spot"}},
+		{Key: "preemptible", Operator: corev1.NodeSelectorOpDoesNotExist},
+	}
+	terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 || !reflect.DeepEqual(terms[0].MatchExpressions, want) {
+		t.Errorf("node selector terms = %+v, want one term matching %+v", terms, want)
+	}
+
+	for _, invalid := range []string{"", "pool in spot", "pool=a=b"} {
+		if _, err := ParseAffinity(invalid); err == nil {
+			t.Errorf("ParseAffinity(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestParseAffinityFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "affinity.yaml")
+	data := `podAntiAffinity:
+  preferredDuringSchedulingIgnoredDuringExecution:
+    - weight: 100
+      podAffinityTerm:
+        topologyKey: kubernetes.io/hostname
+        labelSelector:
+          matchLabels: {app: orders}
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	affinity, err := ParseAffinity(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	terms := affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	if len(terms) != 1 || terms[0].Weight != 100 || terms[0].PodAffinityTerm.TopologyKey != "kubernetes.io/hostname" {
+		t.Errorf("pod anti affinity = %+v", terms)
+	}
+
+	if err := os.WriteFile(path, []byte("nodeAfinity: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseAffinity(path); err == nil {
+		t.Error("ParseAffinity() should reject unknown fields")
+	}
+}
+
+func TestWithSchedulingInitContainers(t *testing.T) {
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+	}
+
+	job := NewTestJob("orders", "shop", "orders-test-script", "test-script.yaml", 2, telemetry.Config{}).
+		WithPackages([]NpmPackage{{Name: "ensure", Kind: "plugin"}}).
+		WithScheduling(WorkerScheduling{Resources: resources}).
+		WithStartBarrier("orders-start-barrier")
+
+	spec := job.Spec.Template.Spec
+	if len(spec.InitContainers) != 2 {
+		t.Fatalf("got %d init containers, want 2", len(spec.InitContainers))
+	}
+	for _, c := range append(spec.InitContainers, spec.Containers...) {
+		if !reflect.DeepEqual(c.Resources, resources) {
+			t.Errorf("%s resources = %+v, want %+v", c.Name, c.Resources, resources)
+		}
+	}
+}
@@ -16,59 +16,198 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/artilleryio/kubectl-artillery/internal/kube"
 	yaml3 "gopkg.in/yaml.v3"
 )
 
-// TestScript defines a simple Artillery test script.
-// Mainly used to generate a test script YAML config.
+// TestScript defines an Artillery test script.
+// Used to generate, parse and update test script YAML configs.
+// Unknown fields are kept in Extra, so parsed test scripts marshal without losing information.
 // See: https://www.artillery.io/docs/guides/guides/test-script-reference
 type TestScript struct {
 	Config    Config     `json:"config" yaml:"config"`
+	Before    *Section   `json:"before,omitempty" yaml:"before,omitempty"`
 	Scenarios []Scenario `json:"scenarios" yaml:"scenarios"`
+	After     *Section   `json:"after,omitempty" yaml:"after,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
 }
 
 // Config defines a test script's config.
 type Config struct {
-	Target       string                 `json:"target" yaml:"target"`
+	Target       string                 `json:"target,omitempty" yaml:"target,omitempty"`
 	Phases       []Phase                `json:"phases,omitempty" yaml:"phases,omitempty"`
 	Environments map[string]Environment `json:"environments,omitempty" yaml:"environments,omitempty"`
+	Variables    map[string]interface{} `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Payload      Payloads               `json:"payload,omitempty" yaml:"payload,omitempty"`
+	Defaults     map[string]interface{} `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+	TLS          *TLS                   `json:"tls,omitempty" yaml:"tls,omitempty"`
+	HTTP         *HTTP                  `json:"http,omitempty" yaml:"http,omitempty"`
+	Plugins      map[string]interface{} `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	Ensure       *Ensure                `json:"ensure,omitempty" yaml:"ensure,omitempty"`
+	Processor    string                 `json:"processor,omitempty" yaml:"processor,omitempty"`
+	Engines      map[string]interface{} `json:"engines,omitempty" yaml:"engines,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
 }
 
 // Phase defines a test script's phase.
+// Durations are seconds, or strings with a unit, e.g. "5m".
 type Phase struct {
-	Duration     int `json:"duration,omitempty" yaml:"duration,omitempty"`
-	ArrivalCount int `json:"arrivalCount,omitempty" yaml:"arrivalCount,omitempty"`
-	ArrivalRate  int `json:"arrivalRate,omitempty" yaml:"arrivalRate,omitempty"`
+	Name         string      `json:"name,omitempty" yaml:"name,omitempty"`
+	Duration     IntOrString `json:"duration,omitempty" yaml:"duration,omitempty"`
+	ArrivalCount IntOrString `json:"arrivalCount,omitempty" yaml:"arrivalCount,omitempty"`
+	ArrivalRate  IntOrString `json:"arrivalRate,omitempty" yaml:"arrivalRate,omitempty"`
+	RampTo       IntOrString `json:"rampTo,omitempty" yaml:"rampTo,omitempty"`
+	MaxVusers    IntOrString `json:"maxVusers,omitempty" yaml:"maxVusers,omitempty"`
+	Pause        IntOrString `json:"pause,omitempty" yaml:"pause,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
 }
 
-// Environment defines a test script's environment.
+// Environment defines a test script's environment, overriding config settings when selected.
 type Environment struct {
-	Phases  []Phase                `json:"phases" yaml:"phases"`
-	Target  string                 `json:"target,omitempty" yaml:"target,omitempty"`
-	Plugins map[string]interface{} `json:"plugins" yaml:"plugins"`
+	Phases    []Phase                `json:"phases,omitempty" yaml:"phases,omitempty"`
+	Target    string                 `json:"target,omitempty" yaml:"target,omitempty"`
+	Variables map[string]interface{} `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Payload   Payloads               `json:"payload,omitempty" yaml:"payload,omitempty"`
+	Defaults  map[string]interface{} `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+	TLS       *TLS                   `json:"tls,omitempty" yaml:"tls,omitempty"`
+	HTTP      *HTTP                  `json:"http,omitempty" yaml:"http,omitempty"`
+	Plugins   map[string]interface{} `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	Ensure    *Ensure                `json:"ensure,omitempty" yaml:"ensure,omitempty"`
+	Processor string                 `json:"processor,omitempty" yaml:"processor,omitempty"`
+	Engines   map[string]interface{} `json:"engines,omitempty" yaml:"engines,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
+
+// Payload defines a CSV file providing variables to virtual users.
+type Payload struct {
+	Path           string   `json:"path,omitempty" yaml:"path,omitempty"`
+	Fields         []string `json:"fields,omitempty" yaml:"fields,omitempty"`
+	Name           string   `json:"name,omitempty" yaml:"name,omitempty"`
+	Order          string   `json:"order,omitempty" yaml:"order,omitempty"`
+	SkipHeader     *bool    `json:"skipHeader,omitempty" yaml:"skipHeader,omitempty"`
+	Delimiter      string   `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`
+	Cast           *bool    `json:"cast,omitempty" yaml:"cast,omitempty"`
+	SkipEmptyLines *bool    `json:"skipEmptyLines,omitempty" yaml:"skipEmptyLines,omitempty"`
+	LoadAll        *bool    `json:"loadAll,omitempty" yaml:"loadAll,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
+
+// TLS defines a test script's TLS settings.
+type TLS struct {
+	RejectUnauthorized *bool `json:"rejectUnauthorized,omitempty" yaml:"rejectUnauthorized,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
+
+// HTTP defines a test script's HTTP settings.
+type HTTP struct {
+	Timeout         IntOrString `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	MaxSockets      IntOrString `json:"maxSockets,omitempty" yaml:"maxSockets,omitempty"`
+	Pool            IntOrString `json:"pool,omitempty" yaml:"pool,omitempty"`
+	ExtendedMetrics *bool       `json:"extendedMetrics,omitempty" yaml:"extendedMetrics,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
+
+// Ensure defines a test script's success conditions, checked once a test run completes.
+// Legacy top level metrics, e.g. p95 or maxErrorRate, are kept in Extra.
+type Ensure struct {
+	Thresholds []map[string]interface{} `json:"thresholds,omitempty" yaml:"thresholds,omitempty"`
+	Conditions []EnsureCondition        `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
+}
+
+// EnsureCondition defines an ensure expression over test run metrics.
+type EnsureCondition struct {
+	Expression string `json:"expression" yaml:"expression"`
+	Strict     *bool  `json:"strict,omitempty" yaml:"strict,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
 }
 
 // Scenario defines a test script's scenario.
+// Engine defaults to HTTP, other engines include ws, socketio and custom engines, e.g. grpc.
 type Scenario struct {
-	Flows []Flow `json:"flow,omitempty" yaml:"flow,omitempty"`
+	Name           string    `json:"name,omitempty" yaml:"name,omitempty"`
+	Engine         string    `json:"engine,omitempty" yaml:"engine,omitempty"`
+	Weight         *int      `json:"weight,omitempty" yaml:"weight,omitempty"`
+	BeforeScenario Functions `json:"beforeScenario,omitempty" yaml:"beforeScenario,omitempty"`
+	AfterScenario  Functions `json:"afterScenario,omitempty" yaml:"afterScenario,omitempty"`
+	BeforeRequest  Functions `json:"beforeRequest,omitempty" yaml:"beforeRequest,omitempty"`
+	AfterResponse  Functions `json:"afterResponse,omitempty" yaml:"afterResponse,omitempty"`
+	Flows          []Flow    `json:"flow,omitempty" yaml:"flow,omitempty"`
+
+	Extra map[string]interface{} `json:"-" yaml:",inline"`
+	nulls `json:"-" yaml:"-"`
 }
 
-// Flow defines a test script's flow.
-type Flow struct {
-	GetFlow GetFlow `json:"get,omitempty" yaml:"get,omitempty"`
+// ParseTestScript parses a test script YAML config.
+func ParseTestScript(data []byte) (*TestScript, error) {
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	var t TestScript
+	if err := doc.Decode(&t); err != nil {
+		return nil, err
+	}
+	readNulls(&doc, &t)
+	return &t, nil
 }
 
-// GetFlow defines a test script's get flow.
-type GetFlow struct {
-	Url    string       `json:"url,omitempty" yaml:"url,omitempty"`
-	Expect []StatusCode `json:"expect,omitempty" yaml:"expect,omitempty"`
+// ReadTestScript reads and parses a test script YAML config file.
+func ReadTestScript(path string) (*TestScript, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := ParseTestScript(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
 }
 
-// StatusCode HTTP status code for a test script's get flow
-type StatusCode struct {
-	Code int `json:"statusCode,omitempty" yaml:"statusCode,omitempty"`
+// newRequestFlow returns a flow for an HTTP method's request.
+func newRequestFlow(method string, request *RequestFlow) Flow {
+	switch strings.ToUpper(method) {
+	case http.MethodPost:
+		return Flow{Post: request}
+	case http.MethodPut:
+		return Flow{Put: request}
+	case http.MethodPatch:
+		return Flow{Patch: request}
+	case http.MethodDelete:
+		return Flow{Delete: request}
+	case http.MethodHead:
+		return Flow{Head: request}
+	case http.MethodOptions:
+		return Flow{Options: request}
+	default:
+		return Flow{Get: request}
+	}
 }
 
 // NewTestScript returns an Artillery test script configured to run HTTP functional tests
@@ -80,11 +219,11 @@ func NewTestScript(probes kube.ServiceProbes) *TestScript {
 		for _, get := range probe.HTTPGets {
 			target.Path = get.Path
 			flow := Flow{
-				GetFlow: GetFlow{
+				Get: &RequestFlow{
 					Url: fmt.Sprintf("%s", target.String()),
-					Expect: []StatusCode{
+					Expect: []Expectation{
 						{
-							Code: 200,
+							StatusCode: 200,
 						},
 					},
 				},
@@ -93,17 +232,16 @@ func NewTestScript(probes kube.ServiceProbes) *TestScript {
 		}
 	}
 
-	testScriptTarget := fmt.Sprintf("%s://%s/", probes[0].Url.Scheme, probes[0].Url.Host)
 	return &TestScript{
 		Config: Config{
-			Target: testScriptTarget,
+			Target: probesTarget(probes),
 
 			Environments: map[string]Environment{
 				"functional": {
 					Phases: []Phase{
 						{
-							Duration:     1,
-							ArrivalCount: 1,
+							Duration:     FromInt(1),
+							ArrivalCount: FromInt(1),
 						},
 					},
 					Plugins: map[string]interface{}{
@@ -120,6 +258,219 @@ func NewTestScript(probes kube.ServiceProbes) *TestScript {
 	}
 }
 
+// probesTarget returns the test script target of probes, the scheme and host of the first probe.
+func probesTarget(probes kube.ServiceProbes) string {
+	if len(probes) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s://%s/", probes[0].Url.Scheme, probes[0].Url.Host)
+}
+
+// NewStreamingTestScript returns an Artillery test script configured to run functional tests
+// for a service port serving a streaming protocol, using the protocol's Artillery engine.
+// WebSocket and Socket.io scenarios send a message, gRPC scenarios are marked for the grpc engine
+// and require method calls to be added.
+func NewStreamingTestScript(svcName string, port kube.ServicePort) *TestScript {
+	scenario := Scenario{Name: fmt.Sprintf("%s %s", svcName, port.Name)}
+	target := port.Url.String()
+	var engines map[string]interface{}
+
+	switch port.Protocol {
+	case kube.ProtocolWS, kube.ProtocolWSS:
+		scenario.Engine = "ws"
+		scenario.Flows = []Flow{
+			{Send: "hello"},
+			{Think: FromInt(1)},
+		}
+	case kube.ProtocolSocketIO:
+		scenario.Engine = "socketio"
+		scenario.Flows = []Flow{
+			{Emit: &EmitFlow{Channel: "message", Data: "hello"}},
+			{Think: FromInt(1)},
+		}
+	case kube.ProtocolGRPC:
+		scenario.Engine = "grpc"
+		scenario.Name = fmt.Sprintf("%s %s, add grpc method calls", svcName, port.Name)
+		target = port.Url.Host
+		engines = map[string]interface{}{
+			"grpc": make(map[string]string),
+		}
+	}
+
+	return &TestScript{
+		Config: Config{
+			Target: target,
+			Environments: map[string]Environment{
+				"functional": {
+					Phases: []Phase{
+						{
+							Duration:     FromInt(1),
+							ArrivalCount: FromInt(1),
+						},
+					},
+					Plugins: map[string]interface{}{},
+				},
+			},
+			Engines: engines,
+		},
+		Scenarios: []Scenario{scenario},
+	}
+}
+
+// NewKafkaTestScript returns an Artillery test script configured to publish messages to a Kafka topic
+// using the kafka engine, at a rate of messages per second for a duration in seconds.
+// The payload is a message data template, e.g. {"id": "{{ $randomString() }}"}
+func NewKafkaTestScript(topic *kube.KafkaTopic, rate, duration int, payload string) *TestScript {
+	return &TestScript{
+		Config: Config{
+			Target: topic.BootstrapServers,
+			Phases: []Phase{
+				{
+					Name:        fmt.Sprintf("publish to %s", topic.TopicName),
+					Duration:    FromInt(duration),
+					ArrivalRate: FromInt(rate),
+				},
+			},
+			Engines: map[string]interface{}{
+				"kafka": make(map[string]string),
+			},
+		},
+		Scenarios: []Scenario{
+			{
+				Name:   fmt.Sprintf("publish to %s", topic.TopicName),
+				Engine: "kafka",
+				Flows: []Flow{
+					{
+						PublishMessage: &PublishMessageFlow{
+							Topic: topic.TopicName,
+							Data:  payload,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// WithAnnotatedEndpoints adds flows testing the endpoints a service declares using annotations.
+// Declared headers are sent with every request.
+func (t *TestScript) WithAnnotatedEndpoints(annotated *kube.AnnotatedEndpoints) *TestScript {
+	if annotated == nil {
+		return t
+	}
+
+	if len(t.Config.Target) == 0 {
+		t.Config.Target = fmt.Sprintf("%s://%s/", annotated.Url.Scheme, annotated.Url.Host)
+	}
+
+	var headers map[string]interface{}
+	for name, value := range annotated.Headers {
+		if headers == nil {
+			headers = map[string]interface{}{}
+		}
+		headers[name] = value
+	}
+
+	target := *annotated.Url
+	for _, endpoint := range annotated.Endpoints {
+		target.Path = endpoint.Path
+		flow := newRequestFlow(endpoint.Method, &RequestFlow{
+			Url:     target.String(),
+			Headers: headers,
+			Expect: []Expectation{
+				{
+					StatusCode: endpoint.StatusCode,
+				},
+			},
+		})
+		t.Scenarios[0].Flows = append(t.Scenarios[0].Flows, flow)
+	}
+	return t
+}
+
+// WithTarget sets the test script target to the first of probes, e.g. a headless Service
+// whose flows target Pods individually.
+func (t *TestScript) WithTarget(probes kube.ServiceProbes) *TestScript {
+	if target := probesTarget(probes); len(target) > 0 {
+		t.Config.Target = target
+	}
+	return t
+}
+
+// WithWarmUp prepends a warm-up phase to every environment's phases.
+// A warm-up absorbs cold starts, e.g. Knative scale-from-zero, before the actual test phases.
+// A duration of 0 or less skips the warm-up.
+func (t *TestScript) WithWarmUp(duration, arrivalRate int) *TestScript {
+	if duration <= 0 {
+		return t
+	}
+
+	warmUp := Phase{
+		Name:        "warm-up",
+		Duration:    FromInt(duration),
+		ArrivalRate: FromInt(arrivalRate),
+	}
+
+	for name, env := range t.Config.Environments {
+		env.Phases = append([]Phase{warmUp}, env.Phases...)
+		t.Config.Environments[name] = env
+	}
+	return t
+}
+
+// Autoscale test phase durations in seconds.
+// The hold phase outlasts HorizontalPodAutoscaler metrics collection and sync periods to observe scale-out.
+const (
+	autoscaleBaselineDuration = 60
+	autoscaleRampDuration     = 120
+	autoscaleHoldDuration     = 300
+)
+
+// autoscaleOvershootPercent how far past the max replicas' arrival rate the autoscale test ramps, in percent.
+// The max replicas' arrival rate only reaches the scaling targets, overshooting it keeps them exceeded.
+const autoscaleOvershootPercent = 150
+
+// WithAutoscaleEnvironment adds an "autoscale" environment with a ramp profile designed to push
+// a workload past its HorizontalPodAutoscaler scaling thresholds, then hold long enough to observe scale-out.
+// ratePerReplica is the arrival rate a single replica is expected to handle at its scaling target,
+// the load ramps past the max replicas' arrival rate, see: autoscaleOvershootPercent.
+func (t *TestScript) WithAutoscaleEnvironment(autoscaling *kube.Autoscaling, ratePerReplica int) *TestScript {
+	minRate := int(autoscaling.MinReplicas) * ratePerReplica
+	maxRate := int(autoscaling.MaxReplicas) * ratePerReplica * autoscaleOvershootPercent / 100
+
+	targets := "scaling targets"
+	if len(autoscaling.Targets) > 0 {
+		targets = strings.Join(autoscaling.Targets, ", ")
+	}
+
+	if t.Config.Environments == nil {
+		t.Config.Environments = map[string]Environment{}
+	}
+
+	t.Config.Environments["autoscale"] = Environment{
+		Phases: []Phase{
+			{
+				Name:        fmt.Sprintf("baseline at %d min replicas", autoscaling.MinReplicas),
+				Duration:    FromInt(autoscaleBaselineDuration),
+				ArrivalRate: FromInt(minRate),
+			},
+			{
+				Name:        fmt.Sprintf("ramp past %s", targets),
+				Duration:    FromInt(autoscaleRampDuration),
+				ArrivalRate: FromInt(minRate),
+				RampTo:      FromInt(maxRate),
+			},
+			{
+				Name:        fmt.Sprintf("hold to observe scale-out to %d max replicas", autoscaling.MaxReplicas),
+				Duration:    FromInt(autoscaleHoldDuration),
+				ArrivalRate: FromInt(maxRate),
+			},
+		},
+		Plugins: map[string]interface{}{},
+	}
+	return t
+}
+
 // MarshalWithIndent marshals a TestScript using a specified indentation.
 func (t *TestScript) MarshalWithIndent(indent int) ([]byte, error) {
 	var out bytes.Buffer
@@ -132,7 +483,13 @@ func (t *TestScript) MarshalWithIndent(indent int) ([]byte, error) {
 	}(encoder)
 	encoder.SetIndent(indent)
 
-	if err := encoder.Encode(t); err != nil {
+	var doc yaml3.Node
+	if err := doc.Encode(t); err != nil {
+		return nil, err
+	}
+	writeNulls(&doc, t)
+
+	if err := encoder.Encode(&doc); err != nil {
 		return nil, err
 	}
 	return out.Bytes(), nil
@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"testing"
+
+	"github.com/artilleryio/kubectl-artillery/internal/kube"
+)
+
+func TestWithWarmUp(t *testing.T) {
+	tests := []struct {
+		duration int
+		phases   int
+	}{
+		{duration: 30, phases: 2},
+		{duration: 0, phases: 1},
+		{duration: -1, phases: 1},
+	}
+
+	for _, tt := range tests {
+		ts := NewTestScript(kube.ServiceProbes{}).WithWarmUp(tt.duration, 1)
+		phases := ts.Config.Environments["functional"].Phases
+		if len(phases) != tt.phases {
+			t.Errorf("WithWarmUp(%d) has %d phases, want %d", tt.duration, len(phases), tt.phases)
+		}
+		if tt.phases == 2 && phases[0].Name != "warm-up" {
+			t.Errorf("WithWarmUp(%d) first phase is %q, want warm-up", tt.duration, phases[0].Name)
+		}
+	}
+}
+
+func TestWithAutoscaleEnvironment(t *testing.T) {
+	autoscaling := &kube.Autoscaling{MinReplicas: 2, MaxReplicas: 10, Targets: []string{"cpu 70%"}}
+	ts := NewTestScript(kube.ServiceProbes{}).WithAutoscaleEnvironment(autoscaling, 10)
+
+	phases := ts.Config.Environments["autoscale"].Phases
+	if len(phases) != 3 {
+		t.Fatalf("got %d phases, want baseline, ramp and hold", len(phases))
+	}
+
+	// max replicas handle 100 arrivals per second at their scaling target, the load ramps past it
+	tests := []struct {
+		name        string
+		arrivalRate int
+		rampTo      int
+	}{
+		{name: "baseline at 2 min replicas", arrivalRate: 20},
+		{name: "ramp past cpu 70%", arrivalRate: 20, rampTo: 150},
+		{name: "hold to observe scale-out to 10 max replicas", arrivalRate: 150},
+	}
+	for i, tt := range tests {
+		p := phases[i]
+		if p.Name != tt.name || p.ArrivalRate.IntVal != tt.arrivalRate || p.RampTo.IntVal != tt.rampTo {
+			t.Errorf("phase %d = %q at %d ramping to %d, want %q at %d ramping to %d",
+				i, p.Name, p.ArrivalRate.IntVal, p.RampTo.IntVal, tt.name, tt.arrivalRate, tt.rampTo)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Service wrapper to enable marshaling a Kubernetes Service to a file.
+type Service struct {
+	*corev1.Service
+}
+
+// MarshalWithIndent marshals a Service using a specified indentation.
+func (s *Service) MarshalWithIndent(indent int) ([]byte, error) {
+	data, err := s.json()
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonToYaml(data, indent)
+}
+
+func (s *Service) json() ([]byte, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var temp map[string]interface{}
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return nil, err
+	}
+	delete(temp, "status")
+	delete(temp["metadata"].(map[string]interface{}), "creationTimestamp")
+
+	return json.Marshal(temp)
+}
@@ -13,6 +13,7 @@
 package artillery
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -20,13 +21,44 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// WorkerImage the Artillery image used by workers to run tests.
+// WorkerImage the default Artillery image used by workers to run tests.
 const WorkerImage = "artilleryio/artillery:latest"
 
 // JobTestScriptVol the volume used by created Pods to load the test script ConfigMap.
 const JobTestScriptVol = "test-script"
 
+// JobDataVol the volume large assets are assembled into, alongside the test script ConfigMap files.
+const JobDataVol = "test-data"
+
+// JobDataMountPath where test workers find the test script and its assets.
+const JobDataMountPath = "/data"
+
+// JobTestScriptMountPath where the init container staging large assets finds the test script ConfigMap.
+const JobTestScriptMountPath = "/test-script"
+
+// JobShardsVol the volume used by created Pods to load the payload shards ConfigMap.
+const JobShardsVol = "payload-shards"
+
+// JobShardsMountPath where test workers find their payload shards.
+const JobShardsMountPath = "/shards"
+
+// JobPluginsVol the volume plugins and engines required by the test script are installed into.
+const JobPluginsVol = "plugins"
+
+// JobPluginsMountPath where test workers find the installed plugins and engines.
+const JobPluginsMountPath = "/plugins"
+
+// AssetsInitImage the image used by the init container staging large assets.
+const AssetsInitImage = "busybox:1.36"
+
 const TestFilename = "test-job.yaml"
+
+// StartBarrierFilename the file name of the RBAC manifests used by test workers waiting for each other to start.
+const StartBarrierFilename = "start-barrier-rbac.yaml"
+
+// AssetsClaimFilename the file name of the PersistentVolumeClaim holding large assets.
+const AssetsClaimFilename = "assets-pvc.yaml"
+
 const LabelPrefix = "artilleryio-test"
 const DefaultManifestDir = "artillery-manifests"
 const DefaultScriptsDir = "artillery-scripts"
@@ -37,6 +69,8 @@ const cliSettingsFilename = ".artillerykuberc"
 type CLISettings struct {
 	File      string                `yaml:"-" json:"-"`
 	Analytics *TelemetryCLISettings `yaml:"kubectl-artillery,omitempty" json:"kubectl-artillery,omitempty"`
+	// Workers the resources and scheduling constraints of generated test workers, see: WorkerScheduling.
+	Workers map[string]interface{} `yaml:"workers,omitempty" json:"workers,omitempty"`
 }
 
 // TelemetryCLISettings telemetry specific settings.
@@ -83,16 +117,29 @@ func GetOrCreateCLISettings() (*CLISettings, error) {
 }
 
 // GetFirstRun returns if this is the first run of kubectl-artillery CLI.
+// Settings files written by hand, e.g. with only a workers section, count as a first run.
 func (s *CLISettings) GetFirstRun() bool {
-	return *s.Analytics.FirstRun
+	return s.Analytics == nil || s.Analytics.FirstRun == nil || *s.Analytics.FirstRun
 }
 
 // SetFirstRun configures the first run of kubectl-artillery CLI.
 func (s *CLISettings) SetFirstRun(b bool) *CLISettings {
+	if s.Analytics == nil {
+		s.Analytics = &TelemetryCLISettings{}
+	}
 	s.Analytics.FirstRun = &b
 	return s
 }
 
+// GetWorkerScheduling returns the resources and scheduling constraints of test workers.
+func (s *CLISettings) GetWorkerScheduling() (WorkerScheduling, error) {
+	scheduling, err := ParseWorkerScheduling(s.Workers)
+	if err != nil {
+		return scheduling, fmt.Errorf("%s: %w", s.File, err)
+	}
+	return scheduling, nil
+}
+
 // Save writes the kubectl-artillery CLI settings to a file.
 func (s *CLISettings) Save() error {
 	data, err := yaml.Marshal(s)
@@ -0,0 +1,224 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// PayloadShards a payload CSV file split into one shard per test worker,
+// so workers of an Indexed Job do not share payload rows.
+type PayloadShards struct {
+	Name  string
+	Rows  int
+	Files []string
+}
+
+// String describes how a payload is sharded.
+func (p PayloadShards) String() string {
+	return fmt.Sprintf("%s sharded, %d rows split across %d workers", p.Name, p.Rows, len(p.Files))
+}
+
+// ShardSubPathExpr returns the expanded subPath of the shard a test worker mounts, selected by its WORKER_INDEX.
+func (p PayloadShards) ShardSubPathExpr() string {
+	return shardFile(p.Name, "$(WORKER_INDEX)")
+}
+
+// ShardPayloads splits every bundled payload CSV file into count shards, written to the bundle directory.
+// Rows are split in order, a header kept by skipHeader is repeated in every shard.
+// Shards are packed into as many ConfigMaps as needed, each shard must fit a ConfigMap.
+// The payload paths of the test script, config and worker scripts split by SplitLoad are rewritten to the shards mount path.
+func (b *Bundle) ShardPayloads(count int) error {
+	if count < 1 {
+		count = 1
+	}
+
+	var size int
+	for _, name := range b.Assets {
+		skipHeader, isPayload := b.payloads[name]
+		if !isPayload {
+			continue
+		}
+
+		shards, sizes, err := b.shardPayload(name, skipHeader, count)
+		if err != nil {
+			return err
+		}
+		b.Shards = append(b.Shards, shards)
+
+		for i, file := range shards.Files {
+			if sizes[i] > MaxConfigMapSize {
+				return fmt.Errorf("%s is %s, too large for a ConfigMap, use a larger --count, "+
+					"or drop --shard-payloads to deliver %s whole using --large-assets",
+					file, humanSize(int64(sizes[i])), name)
+			}
+			if len(b.ShardConfigMaps) == 0 || size+sizes[i] > MaxConfigMapSize {
+				b.ShardConfigMaps = append(b.ShardConfigMaps, nil)
+				size = 0
+			}
+			last := len(b.ShardConfigMaps) - 1
+			b.ShardConfigMaps[last] = append(b.ShardConfigMaps[last], file)
+			size += sizes[i]
+		}
+	}
+
+	if len(b.Shards) == 0 {
+		return fmt.Errorf("%s has no payload to shard", b.Script)
+	}
+
+	for _, name := range append([]string{b.Script, b.Config}, b.Workers...) {
+		if len(name) == 0 {
+			continue
+		}
+		if err := b.rewritePayloadPaths(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsSharded checks whether a bundled file is a sharded payload.
+func (b *Bundle) IsSharded(name string) bool {
+	for _, p := range b.Shards {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ShardConfigMapNames returns the names of the payload shards ConfigMaps, numbered when there is more than one.
+func (b *Bundle) ShardConfigMapNames(prefix string) []string {
+	if len(b.ShardConfigMaps) == 1 {
+		return []string{prefix}
+	}
+
+	var names []string
+	for i := range b.ShardConfigMaps {
+		names = append(names, fmt.Sprintf("%s-%d", prefix, i))
+	}
+	return names
+}
+
+// shardPayload splits a bundled payload CSV file into count shard files, returning the size of each.
+func (b *Bundle) shardPayload(name string, skipHeader bool, count int) (PayloadShards, []int, error) {
+	path := filepath.Join(b.Dir, name)
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return PayloadShards{}, nil, err
+	}
+
+	rows := csvRecords(data)
+	var header []byte
+	if skipHeader && len(rows) > 0 {
+		header, rows = rows[0], rows[1:]
+	}
+	if len(rows) < count {
+		return PayloadShards{}, nil, fmt.Errorf("%s has %d rows, fewer than the %d workers sharing it", name, len(rows), count)
+	}
+
+	shards := PayloadShards{Name: name, Rows: len(rows)}
+	var (
+		sizes []int
+		start int
+	)
+	for i := 0; i < count; i++ {
+		// spread the remainder over the first shards
+		end := start + len(rows)/count
+		if i < len(rows)%count {
+			end++
+		}
+
+		shard := append([]byte{}, header...)
+		for _, row := range rows[start:end] {
+			shard = append(shard, row...)
+		}
+		start = end
+
+		file := shardFile(name, fmt.Sprint(i))
+		if err := os.WriteFile(filepath.Join(b.Dir, file), shard, 0644); err != nil {
+			return PayloadShards{}, nil, err
+		}
+		shards.Files = append(shards.Files, file)
+		sizes = append(sizes, len(shard))
+	}
+
+	// keep the source file when bundling into its own directory
+	if abs, _ := filepath.Abs(path); b.sources[name] != abs {
+		if err := os.Remove(path); err != nil {
+			return PayloadShards{}, nil, err
+		}
+	}
+	return shards, sizes, nil
+}
+
+// rewritePayloadPaths rewrites the paths of sharded payloads in a bundled test script or config file
+// to the shards mount path, where each test worker finds its own shard.
+func (b *Bundle) rewritePayloadPaths(name string) error {
+	path := filepath.Join(b.Dir, name)
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	var edits []lineEdit
+	for ref := range payloadReferences(mappingValue(&doc, "config")) {
+		if b.IsSharded(ref.Value) {
+			edits = append(edits, lineEdit{line: ref.Line, old: ref.Value, new: JobShardsMountPath + "/" + ref.Value})
+		}
+	}
+
+	rewritten, _ := applyLineEdits(data, edits)
+	return os.WriteFile(path, rewritten, 0644)
+}
+
+// shardFile returns the file name of a payload shard.
+func shardFile(name, index string) string {
+	return fmt.Sprintf("%s.shard-%s", name, index)
+}
+
+// csvRecords splits CSV data into records, each with its line ending.
+// Quoted fields may span lines, empty lines are dropped.
+func csvRecords(data []byte) [][]byte {
+	var (
+		records [][]byte
+		start   int
+		quoted  bool
+	)
+	for i, c := range data {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == '\n' && !quoted:
+			if record := data[start : i+1]; len(bytes.TrimSpace(record)) > 0 {
+				records = append(records, record)
+			}
+			start = i + 1
+		}
+	}
+
+	if record := data[start:]; len(bytes.TrimSpace(record)) > 0 {
+		records = append(records, append(append([]byte{}, record...), '\n'))
+	}
+	return records
+}
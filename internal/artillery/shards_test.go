@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/artilleryio/kubectl-artillery/internal/telemetry"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCsvRecords(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "lines",
+			data: "id,name\n1,ada\n2,grace\n",
+			want: []string{"id,name\n", "1,ada\n", "2,grace\n"},
+		},
+		{
+			name: "no trailing line ending",
+			data: "1,ada\n2,grace",
+			want: []string{"1,ada\n", "2,grace\n"},
+		},
+		{
+			name: "crlf and empty lines",
+			data: "1,ada\r\n\r\n\n2,grace\r\n",
+			want: []string{"1,ada\r\n", "2,grace\r\n"},
+		},
+		{
+			name: "quoted line break",
+			data: "1,\"12 Main St\nSpringfield\"\n2,\"say \"\"hi\"\"\"\n",
+			want: []string{"1,\"12 Main St\nSpringfield\"\n", "2,\"say \"\"hi\"\"\"\n"},
+		},
+		{
+			name: "empty",
+			data: "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, record := range csvRecords([]byte(tt.data)) {
+				got = append(got, string(record))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("csvRecords() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShardPayloads(t *testing.T) {
+	script := strings.Replace(payloadScript, "fields: [id]", "fields: [id]\n    skipHeader: true", 1)
+	b := bundleFiles(t, script, map[string][]byte{"users.csv": []byte("id\n1\n2\n3\n4\n5\n")})
+
+	if err := b.ShardPayloads(2); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Shards) != 1 || b.Shards[0].Rows != 5 {
+		t.Fatalf("shards = %v, want users.csv with 5 rows", b.Shards)
+	}
+
+	// the header is repeated, the remainder goes to the first shard
+	want := []string{"id\n1\n2\n3\n", "id\n4\n5\n"}
+	for i, file := range b.Shards[0].Files {
+		data, err := os.ReadFile(filepath.Join(b.Dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want[i] {
+			t.Errorf("shard %d = %q, want %q", i, data, want[i])
+		}
+	}
+
+	b = bundleFiles(t, script, map[string][]byte{"users.csv": []byte("id\n1\n2\n3\n4\n5\n")})
+	if err := b.ShardPayloads(6); err == nil || !strings.Contains(err.Error(), "fewer than the 6 workers") {
+		t.Errorf("error = %v, want too few rows for 6 workers", err)
+	}
+
+	b = bundleFiles(t, payloadScript, map[string][]byte{"users.csv": []byte("id\n1\n2\n")})
+	if err := b.ShardPayloads(2); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.ShardConfigMapNames("orders-payload-shards"); !reflect.DeepEqual(got, []string{"orders-payload-shards"}) {
+		t.Errorf("ShardConfigMapNames() = %v, want a single ConfigMap", got)
+	}
+}
+
+func TestShardPayloadsConfigMaps(t *testing.T) {
+	row := strings.Repeat("x", 1023) + "\n"
+	rows := strings.Repeat(row, MaxConfigMapSize/1024*3/2)
+
+	// each shard fits a ConfigMap, the shards together do not
+	b := bundleFiles(t, payloadScript, map[string][]byte{"users.csv": []byte(rows)})
+	if err := b.ShardPayloads(2); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.ShardConfigMaps) != 2 {
+		t.Fatalf("ShardConfigMaps = %v, want a ConfigMap per shard", b.ShardConfigMaps)
+	}
+	want := []string{"orders-payload-shards-0", "orders-payload-shards-1"}
+	if got := b.ShardConfigMapNames("orders-payload-shards"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ShardConfigMapNames() = %v, want %v", got, want)
+	}
+
+	job := NewTestJob("orders", "shop", "orders-test-script", "test-script.yaml", 2, telemetry.Config{}).
+		WithPayloadShards(b.ShardConfigMapNames("orders-payload-shards"), b.Shards)
+	var projected *corev1.ProjectedVolumeSource
+	for _, v := range job.Spec.Template.Spec.Volumes {
+		if v.Name == JobShardsVol {
+			projected = v.Projected
+		}
+	}
+	if projected == nil || len(projected.Sources) != 2 || projected.Sources[1].ConfigMap.Name != want[1] {
+		t.Errorf("shards volume = %v, want projected from %v", projected, want)
+	}
+
+	// a single shard too large names the workaround
+	b = bundleFiles(t, payloadScript, map[string][]byte{"users.csv": []byte(rows)})
+	if err := b.ShardPayloads(1); err == nil || !strings.Contains(err.Error(), "--large-assets") {
+		t.Errorf("error = %v, want too large shard suggesting --large-assets", err)
+	}
+}
@@ -0,0 +1,292 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// splitKeys the phase settings divided across test workers, arrival settings first.
+var splitKeys = []string{"arrivalRate", "arrivalCount", "rampTo", "maxVusers"}
+
+// LoadSplit the phases of a test script divided across test workers,
+// so the aggregate arrival profile of all workers matches the test script.
+type LoadSplit struct {
+	Count  int
+	Phases []PhaseSplit
+}
+
+// PhaseSplit the settings of a phase divided across test workers.
+type PhaseSplit struct {
+	// Environment the environment defining the phase, empty for config.phases.
+	Environment string
+	Index       int
+	Name        string
+	Values      []SplitValue
+}
+
+// SplitValue a phase setting divided across test workers, Shares[i] is the value of worker i.
+type SplitValue struct {
+	Key    string
+	Total  int
+	Shares []int
+}
+
+// String returns the plan of a LoadSplit, one line per phase.
+func (l *LoadSplit) String() string {
+	lines := []string{
+		fmt.Sprintf("load split across %d workers, per worker:", l.Count),
+	}
+	for _, p := range l.Phases {
+		lines = append(lines, "  "+p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// String describes how a phase is divided, e.g. phase 0 "warm up": arrivalRate 10 = 4 + 3 + 3.
+func (p PhaseSplit) String() string {
+	phase := fmt.Sprintf("phase %d", p.Index)
+	if len(p.Environment) > 0 {
+		phase = fmt.Sprintf("environments.%s %s", p.Environment, phase)
+	}
+	if len(p.Name) > 0 {
+		phase = fmt.Sprintf("%s %q", phase, p.Name)
+	}
+
+	var values []string
+	for _, v := range p.Values {
+		var shares []string
+		for _, share := range v.Shares {
+			shares = append(shares, strconv.Itoa(share))
+		}
+		values = append(values, fmt.Sprintf("%s %d = %s", v.Key, v.Total, strings.Join(shares, " + ")))
+	}
+
+	var paused []string
+	for worker := range p.Values[0].Shares {
+		if p.pauses(worker) {
+			paused = append(paused, strconv.Itoa(worker))
+		}
+	}
+	s := fmt.Sprintf("%s: %s", phase, strings.Join(values, ", "))
+	if len(paused) > 0 {
+		s += fmt.Sprintf(" (workers %s pause)", strings.Join(paused, ", "))
+	}
+	return s
+}
+
+// pauses checks whether a worker gets no arrivals in a phase, running it as a pause.
+func (p PhaseSplit) pauses(worker int) bool {
+	arrivals := false
+	for _, v := range p.Values {
+		if v.Key == "maxVusers" {
+			continue
+		}
+		arrivals = true
+		if v.Shares[worker] > 0 {
+			return false
+		}
+	}
+	return arrivals
+}
+
+// SplitLoad divides the phases of the bundled test script, in config and environments, across count test workers.
+// Each worker's test script is written to the bundle directory, see: WorkerScript.
+// Phases set by an external config file cannot be divided.
+func (b *Bundle) SplitLoad(count int) (*LoadSplit, error) {
+	if len(b.Config) > 0 {
+		if err := checkNoPhases(filepath.Join(b.Dir, b.Config)); err != nil {
+			return nil, err
+		}
+	}
+
+	path := filepath.Join(b.Dir, b.Script)
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &LoadSplit{Count: count}
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, phases := range configPhases(mappingValue(&doc, "config")) {
+		for i, phase := range phases.phases.Content {
+			split, err := splitPhase(b.Script, phase, phases.environment, i, count)
+			if err != nil {
+				return nil, err
+			}
+			if len(split.Values) > 0 {
+				plan.Phases = append(plan.Phases, split)
+			}
+		}
+	}
+
+	for worker := 0; worker < count; worker++ {
+		if err := b.writeWorkerScript(data, plan, worker); err != nil {
+			return nil, err
+		}
+		b.Workers = append(b.Workers, b.WorkerScript(strconv.Itoa(worker)))
+	}
+	return plan, nil
+}
+
+// WorkerScript returns the bundled test script file name of a test worker,
+// its index may be an expanded environment variable, e.g. $(WORKER_INDEX).
+func (b *Bundle) WorkerScript(index string) string {
+	ext := filepath.Ext(b.Script)
+	return fmt.Sprintf("%s.worker-%s%s", strings.TrimSuffix(b.Script, ext), index, ext)
+}
+
+// writeWorkerScript writes the test script of a worker, using its share of every divided phase setting.
+// A phase without arrivals for the worker becomes a pause of the same duration.
+func (b *Bundle) writeWorkerScript(data []byte, plan *LoadSplit, worker int) error {
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	next := 0
+	for _, phases := range configPhases(mappingValue(&doc, "config")) {
+		for _, phase := range phases.phases.Content {
+			if next == len(plan.Phases) || !isSplitPhase(phase) {
+				continue
+			}
+			split := plan.Phases[next]
+			next++
+
+			if split.pauses(worker) && mappingValue(phase, "duration") != nil {
+				pause := &yaml3.Node{Kind: yaml3.MappingNode}
+				if name := mappingValue(phase, "name"); name != nil {
+					setMappingValue(pause, "name", name)
+				}
+				setMappingValue(pause, "pause", mappingValue(phase, "duration"))
+				*phase = *pause
+				continue
+			}
+
+			for _, v := range split.Values {
+				setMappingValue(phase, v.Key, &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!int", Value: strconv.Itoa(v.Shares[worker])})
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	encoder := yaml3.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(b.Dir, b.WorkerScript(strconv.Itoa(worker))), out.Bytes(), 0644)
+}
+
+// environmentPhases the phases of a config or one of its environments.
+type environmentPhases struct {
+	environment string
+	phases      *yaml3.Node
+}
+
+// configPhases returns the phases of a config and its environments, in file order.
+func configPhases(config *yaml3.Node) []environmentPhases {
+	var all []environmentPhases
+	if phases := mappingValue(config, "phases"); phases != nil && phases.Kind == yaml3.SequenceNode {
+		all = append(all, environmentPhases{phases: phases})
+	}
+
+	environments := mappingValue(config, "environments")
+	if environments != nil && environments.Kind == yaml3.MappingNode {
+		for i := 0; i+1 < len(environments.Content); i += 2 {
+			phases := mappingValue(environments.Content[i+1], "phases")
+			if phases != nil && phases.Kind == yaml3.SequenceNode {
+				all = append(all, environmentPhases{environment: environments.Content[i].Value, phases: phases})
+			}
+		}
+	}
+	return all
+}
+
+// checkNoPhases checks an external config file defines no phases, these are not divided across test workers.
+func checkNoPhases(path string) error {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if phases := configPhases(mappingValue(&doc, "config")); len(phases) > 0 {
+		return fmt.Errorf("%s:%d:%d: cannot split the load of phases defined in a config file, move them to the test script",
+			path, phases[0].phases.Line, phases[0].phases.Column)
+	}
+	return nil
+}
+
+// isSplitPhase checks whether a phase has settings divided across test workers.
+func isSplitPhase(phase *yaml3.Node) bool {
+	for _, key := range splitKeys {
+		if mappingValue(phase, key) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPhase divides the settings of a phase across count test workers.
+// Remainders go to one more worker each, starting at the phase index, so the first worker is not always the busiest.
+func splitPhase(path string, phase *yaml3.Node, environment string, index, count int) (PhaseSplit, error) {
+	split := PhaseSplit{Environment: environment, Index: index}
+	if name := mappingValue(phase, "name"); nonEmptyScalar(name) {
+		split.Name = name.Value
+	}
+
+	for _, key := range splitKeys {
+		node := mappingValue(phase, key)
+		if node == nil {
+			continue
+		}
+
+		total, err := strconv.Atoi(node.Value)
+		if err != nil || total < 0 {
+			return PhaseSplit{}, fmt.Errorf("%s:%d:%d: %s %q cannot be split across workers, use a number",
+				path, node.Line, node.Column, key, node.Value)
+		}
+		if key == "maxVusers" && total < count {
+			return PhaseSplit{}, fmt.Errorf("%s:%d:%d: maxVusers %d is below the %d workers, every worker needs at least 1",
+				path, node.Line, node.Column, total, count)
+		}
+
+		shares := make([]int, count)
+		for worker := range shares {
+			shares[worker] = total / count
+		}
+		for i := 0; i < total%count; i++ {
+			shares[(index+i)%count]++
+		}
+		split.Values = append(split.Values, SplitValue{Key: key, Total: total, Shares: shares})
+	}
+	return split, nil
+}
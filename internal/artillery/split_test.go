@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+func TestSplitPhase(t *testing.T) {
+	tests := []struct {
+		name   string
+		phase  string
+		index  int
+		count  int
+		want   []SplitValue
+		errMsg string
+	}{
+		{
+			name:  "even",
+			phase: "{duration: 60, arrivalRate: 9}",
+			count: 3,
+			want:  []SplitValue{{Key: "arrivalRate", Total: 9, Shares: []int{3, 3, 3}}},
+		},
+		{
+			name:  "remainder starts at the phase index",
+			phase: "{duration: 60, arrivalRate: 10, rampTo: 20, maxVusers: 5}",
+			index: 1,
+			count: 3,
+			want: []SplitValue{
+				{Key: "arrivalRate", Total: 10, Shares: []int{3, 4, 3}},
+				{Key: "rampTo", Total: 20, Shares: []int{6, 7, 7}},
+				{Key: "maxVusers", Total: 5, Shares: []int{1, 2, 2}},
+			},
+		},
+		{
+			name:  "fewer arrivals than workers",
+			phase: "{duration: 60, arrivalCount: 1}",
+			count: 3,
+			want:  []SplitValue{{Key: "arrivalCount", Total: 1, Shares: []int{1, 0, 0}}},
+		},
+		{
+			name:  "pause",
+			phase: "{pause: 10}",
+			count: 2,
+		},
+		{
+			name:   "templated",
+			phase:  `{duration: 60, arrivalRate: "{{ rate }}"}`,
+			count:  2,
+			errMsg: `arrivalRate "{{ rate }}" cannot be split across workers`,
+		},
+		{
+			name:   "maxVusers below workers",
+			phase:  "{duration: 60, arrivalRate: 10, maxVusers: 1}",
+			count:  2,
+			errMsg: "maxVusers 1 is below the 2 workers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml3.Node
+			if err := yaml3.Unmarshal([]byte(tt.phase), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			split, err := splitPhase("test-script.yaml", doc.Content[0], "", tt.index, tt.count)
+			if len(tt.errMsg) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("error = %v, want %q", err, tt.errMsg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(split.Values, tt.want) {
+				t.Errorf("values = %v, want %v", split.Values, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitLoadShardPayloads(t *testing.T) {
+	script := strings.Replace(payloadScript, "arrivalRate: 1", "arrivalRate: 10", 1)
+	b := bundleFiles(t, script, map[string][]byte{"users.csv": []byte("1\n2\n3\n4\n")})
+
+	plan, err := b.SplitLoad(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := plan.Phases[0].Values[0].Shares; !reflect.DeepEqual(got, []int{5, 5}) {
+		t.Errorf("arrivalRate shares = %v, want [5 5]", got)
+	}
+	if err := b.ShardPayloads(2); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "path: " + JobShardsMountPath + "/users.csv"
+	for _, name := range append([]string{b.Script}, b.Workers...) {
+		data, err := os.ReadFile(filepath.Join(b.Dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s does not read the payload shard, want %q in:\n%s", name, want, data)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// RemovedEndpointComment marks request steps for endpoints no longer scaffolded from a Service.
+const RemovedEndpointComment = "artillery: endpoint no longer scaffolded, remove it or use --prune"
+
+// requestMethods flow step keys holding a request.
+var requestMethods = []string{"get", "post", "put", "patch", "delete", "head", "options"}
+
+// scaffoldedRequestKeys request keys scaffold generates, requests with other keys were edited by hand.
+var scaffoldedRequestKeys = map[string]bool{"url": true, "headers": true, "json": true, "expect": true}
+
+// TestScriptUpdate an existing test script file updated with a scaffolded TestScript.
+// Everything already in the file is kept, only new request steps, scenarios,
+// environments and variables are added.
+type TestScriptUpdate struct {
+	Path     string
+	Original []byte
+	Updated  []byte
+	Added    int
+	Removed  int
+	// changed whether any node of the test script file was added, removed or commented.
+	changed bool
+}
+
+// NewTestScriptUpdate updates the test script file at path with a scaffolded TestScript.
+// Request steps targeting the scaffolded hosts that were not scaffolded again
+// are marked with a comment, or dropped when prune is set.
+// The file is kept as is unless it changes, changed files keep their own indentation, defaulting to indent.
+func NewTestScriptUpdate(path string, scaffolded *TestScript, prune bool, indent int) (*TestScriptUpdate, error) {
+	original, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(original, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if doc.Kind != yaml3.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml3.MappingNode {
+		return nil, fmt.Errorf("%s: not a test script", path)
+	}
+
+	var scaffoldedDoc yaml3.Node
+	if err := scaffoldedDoc.Encode(scaffolded); err != nil {
+		return nil, err
+	}
+
+	u := &TestScriptUpdate{Path: path, Original: original}
+	u.updateConfig(doc.Content[0], &scaffoldedDoc)
+	u.updateScenarios(doc.Content[0], &scaffoldedDoc, scaffolded.hosts(), prune)
+
+	if !u.changed {
+		u.Updated = original
+		return u, nil
+	}
+
+	var out bytes.Buffer
+	encoder := yaml3.NewEncoder(&out)
+	encoder.SetIndent(fileIndent(original, indent))
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	u.Updated = out.Bytes()
+	return u, nil
+}
+
+// HasChanges checks whether updating changes the test script file.
+func (u *TestScriptUpdate) HasChanges() bool {
+	return !bytes.Equal(u.Original, u.Updated)
+}
+
+// Diff returns a unified diff of the test script file's changes.
+func (u *TestScriptUpdate) Diff() string {
+	return Diff(u.Path, u.Original, u.Updated)
+}
+
+// MarshalWithIndent returns the updated test script file, indented when it was updated.
+func (u *TestScriptUpdate) MarshalWithIndent(_ int) ([]byte, error) {
+	return u.Updated, nil
+}
+
+// updateConfig adds a missing target, environments and variables to an existing config.
+// Existing phases, environments and plugins are kept as is.
+func (u *TestScriptUpdate) updateConfig(root, scaffolded *yaml3.Node) {
+	from := mappingValue(scaffolded, "config")
+	if from == nil {
+		return
+	}
+
+	config := mappingValue(root, "config")
+	if config == nil {
+		setMappingValue(root, "config", from)
+		u.changed = true
+		return
+	}
+
+	if target := mappingValue(from, "target"); target != nil && target.Value != "" {
+		existing := mappingValue(config, "target")
+		if existing == nil || existing.Value == "" {
+			setMappingValue(config, "target", target)
+			u.changed = true
+		}
+	}
+
+	for _, key := range []string{"environments", "variables", "engines"} {
+		if addMissingKeys(config, key, mappingValue(from, key)) {
+			u.changed = true
+		}
+	}
+}
+
+// updateScenarios adds new scenarios and request steps to existing scenarios,
+// matching scenarios by name and request steps by method and url.
+func (u *TestScriptUpdate) updateScenarios(root, scaffolded *yaml3.Node, hosts map[string]bool, prune bool) {
+	from := mappingValue(scaffolded, "scenarios")
+	if from == nil {
+		return
+	}
+
+	scenarios := mappingValue(root, "scenarios")
+	if scenarios == nil {
+		setMappingValue(root, "scenarios", from)
+		u.changed = true
+		return
+	}
+
+	for _, scenario := range from.Content {
+		existing := matchingScenario(scenarios, scenario)
+		if existing == nil {
+			scenarios.Content = append(scenarios.Content, scenario)
+			u.Added += len(requestSteps(scenario))
+			u.changed = true
+			continue
+		}
+		u.updateFlow(existing, scenario, hosts, prune)
+	}
+}
+
+// updateFlow adds new request steps of a scaffolded scenario to an existing scenario,
+// and marks or prunes scaffolded request steps that are no longer scaffolded.
+func (u *TestScriptUpdate) updateFlow(existing, scaffolded *yaml3.Node, hosts map[string]bool, prune bool) {
+	flow := mappingValue(existing, "flow")
+	if flow == nil {
+		setMappingValue(existing, "flow", mappingValue(scaffolded, "flow"))
+		u.Added += len(requestSteps(scaffolded))
+		u.changed = true
+		return
+	}
+
+	wanted := map[string]bool{}
+	for _, step := range requestSteps(scaffolded) {
+		key := requestKey(step)
+		wanted[key] = true
+		if findRequestStep(flow, key) == nil {
+			flow.Content = append(flow.Content, step)
+			u.Added++
+			u.changed = true
+		}
+	}
+
+	var kept []*yaml3.Node
+	for _, step := range flow.Content {
+		key := requestKey(step)
+		if key == "" || wanted[key] || !isScaffoldedRequest(step, hosts) {
+			kept = append(kept, step)
+			continue
+		}
+
+		u.Removed++
+		if prune {
+			u.changed = true
+			continue
+		}
+		if !strings.Contains(step.HeadComment, RemovedEndpointComment) {
+			step.HeadComment = strings.TrimSpace(step.HeadComment + "\n# " + RemovedEndpointComment)
+			u.changed = true
+		}
+		kept = append(kept, step)
+	}
+	flow.Content = kept
+}
+
+// hosts returns the hosts targeted by a TestScript's requests.
+func (t *TestScript) hosts() map[string]bool {
+	hosts := map[string]bool{}
+	if h := urlHost(t.Config.Target); h != "" {
+		hosts[h] = true
+	}
+	for _, scenario := range t.Scenarios {
+		for _, step := range scenario.Flows {
+			for _, reqs := range step.Requests() {
+				for _, req := range reqs {
+					if h := urlHost(req.Url); h != "" {
+						hosts[h] = true
+					}
+				}
+			}
+		}
+	}
+	return hosts
+}
+
+// matchingScenario finds the existing scenario matching a scaffolded scenario by name,
+// an unnamed scaffolded scenario matches the first scenario.
+func matchingScenario(scenarios, scenario *yaml3.Node) *yaml3.Node {
+	name := mappingValue(scenario, "name")
+	for _, existing := range scenarios.Content {
+		if name == nil {
+			return existing
+		}
+		if n := mappingValue(existing, "name"); n != nil && n.Value == name.Value {
+			return existing
+		}
+	}
+	return nil
+}
+
+// requestSteps returns the request steps of a scenario's flow.
+func requestSteps(scenario *yaml3.Node) []*yaml3.Node {
+	flow := mappingValue(scenario, "flow")
+	if flow == nil {
+		return nil
+	}
+
+	var steps []*yaml3.Node
+	for _, step := range flow.Content {
+		if requestKey(step) != "" {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// findRequestStep finds a request step in a flow by its request key.
+func findRequestStep(flow *yaml3.Node, key string) *yaml3.Node {
+	for _, step := range flow.Content {
+		if requestKey(step) == key {
+			return step
+		}
+	}
+	return nil
+}
+
+// requestKey returns a request step's method and url, or an empty string for other steps.
+func requestKey(step *yaml3.Node) string {
+	method, req := request(step)
+	if req == nil {
+		return ""
+	}
+
+	u := mappingValue(req, "url")
+	if u == nil {
+		return ""
+	}
+	return method + " " + u.Value
+}
+
+// request returns a request step's method and request.
+func request(step *yaml3.Node) (string, *yaml3.Node) {
+	for _, method := range requestMethods {
+		if req := mappingValue(step, method); req != nil && req.Kind == yaml3.MappingNode {
+			return method, req
+		}
+	}
+	return "", nil
+}
+
+// isScaffoldedRequest checks whether a request step looks scaffolded: it targets a scaffolded host
+// and only has the url, headers, json and status code expectations scaffold generates.
+func isScaffoldedRequest(step *yaml3.Node, hosts map[string]bool) bool {
+	_, req := request(step)
+	if req == nil || !hosts[urlHost(mappingValue(req, "url").Value)] {
+		return false
+	}
+
+	for i := 0; i < len(req.Content); i += 2 {
+		if !scaffoldedRequestKeys[req.Content[i].Value] {
+			return false
+		}
+	}
+
+	if expect := mappingValue(req, "expect"); expect != nil {
+		for _, e := range expect.Content {
+			if mappingValue(e, "statusCode") == nil || len(e.Content) != 2 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// urlHost returns a url's host, or an empty string for relative and invalid urls.
+func urlHost(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// addMissingKeys adds the keys of a scaffolded mapping that are missing from an existing mapping's key,
+// returning whether any key was added.
+func addMissingKeys(parent *yaml3.Node, key string, from *yaml3.Node) bool {
+	if from == nil || from.Kind != yaml3.MappingNode || len(from.Content) == 0 {
+		return false
+	}
+
+	existing := mappingValue(parent, key)
+	if existing == nil {
+		setMappingValue(parent, key, from)
+		return true
+	}
+	if existing.Kind != yaml3.MappingNode {
+		return false
+	}
+
+	added := false
+	for i := 0; i < len(from.Content); i += 2 {
+		if mappingValue(existing, from.Content[i].Value) == nil {
+			existing.Content = append(existing.Content, from.Content[i], from.Content[i+1])
+			added = true
+		}
+	}
+	return added
+}
+
+// fileIndent returns the indentation of a YAML file, the smallest indentation of its lines,
+// or a default indent for files without indented lines.
+func fileIndent(data []byte, indent int) int {
+	found := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if n := len(line) - len(trimmed); n > 0 && (found == 0 || n < found) {
+			found = n
+		}
+	}
+	if found < 2 {
+		return indent
+	}
+	return found
+}
+
+// mappingValue returns the value of a mapping node's key, or nil when missing.
+func mappingValue(node *yaml3.Node, key string) *yaml3.Node {
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml3.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml3.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets the value of a mapping node's key, adding the key when missing.
+func setMappingValue(node *yaml3.Node, key string, value *yaml3.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+
+	node.Content = append(node.Content, &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: key}, value)
+}
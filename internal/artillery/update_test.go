@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artilleryio/kubectl-artillery/internal/kube"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// handEdited a test script using 4 space indentation, flow style and comments, as edited by hand.
+const handEdited = `# orders smoke test
+config:
+    target: "http://orders:80/"
+    environments:
+        functional:
+            phases: [{duration: 1, arrivalCount: 1}]
+            plugins: {expect: {}}
+scenarios:
+    - flow:
+          # health check
+          - get:
+                url: "http://orders:80/health"
+                expect:
+                    - statusCode: 200
+`
+
+// ordersTestScript returns a scaffolded test script testing orders paths.
+func ordersTestScript(paths ...string) *TestScript {
+	var gets []*corev1.HTTPGetAction
+	for _, path := range paths {
+		gets = append(gets, &corev1.HTTPGetAction{Path: path})
+	}
+	return NewTestScript(kube.ServiceProbes{
+		{Url: &url.URL{Scheme: "http", Host: "orders:80"}, HTTPGets: gets, Protocol: kube.ProtocolHTTP},
+	})
+}
+
+func TestNewTestScriptUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test-script_orders.yaml")
+	if err := os.WriteFile(path, []byte(handEdited), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := NewTestScriptUpdate(path, ordersTestScript("/health"), false, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.HasChanges() || string(u.Updated) != handEdited {
+		t.Errorf("unchanged endpoints rewrote the test script:\n%s", u.Diff())
+	}
+
+	u, err = NewTestScriptUpdate(path, ordersTestScript("/health", "/ready"), false, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.HasChanges() || u.Added != 1 || u.Removed != 0 {
+		t.Fatalf("update added %d, removed %d endpoints, want 1 added", u.Added, u.Removed)
+	}
+	for _, want := range []string{"# health check", "\n    target: ", "url: http://orders:80/ready"} {
+		if !strings.Contains(string(u.Updated), want) {
+			t.Errorf("updated test script has no %q:\n%s", want, u.Updated)
+		}
+	}
+}
+
+func TestFileIndent(t *testing.T) {
+	tests := []struct {
+		data string
+		want int
+	}{
+		{data: handEdited, want: 4},
+		{data: "config:\n  target: http://orders\n", want: 2},
+		{data: "scenarios:\n- flow: []\n", want: 2},
+	}
+
+	for _, tt := range tests {
+		if got := fileIndent([]byte(tt.data), 2); got != tt.want {
+			t.Errorf("fileIndent(%q) = %d, want %d", tt.data, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// yamlErrorLine matches the line of YAML syntax and type errors.
+var yamlErrorLine = regexp.MustCompile(`line (\d+): `)
+
+// topLevelKeys test script top level sections.
+var topLevelKeys = map[string]bool{"config": true, "scenarios": true, "before": true, "after": true}
+
+// builtinEngines Artillery engines that do not need to be configured in config.engines.
+var builtinEngines = map[string]bool{"": true, "http": true, "ws": true, "socketio": true}
+
+// ValidationError a test script problem at a file position.
+// A Warning is a likely mistake Artillery ignores, e.g. an unknown top level section.
+type ValidationError struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+	Warning bool
+}
+
+// Error returns a ValidationError as file:line:column: message, warnings as file:line:column: warning: message.
+func (e ValidationError) Error() string {
+	if e.Warning {
+		return fmt.Sprintf("%s:%d:%d: warning: %s", e.Path, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.Path, e.Line, e.Column, e.Message)
+}
+
+// ValidationErrors all problems found in a test script.
+type ValidationErrors []ValidationError
+
+// Error returns all ValidationErrors, one per line.
+func (es ValidationErrors) Error() string {
+	var lines []string
+	for _, e := range es {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Failed checks whether any ValidationError is not a warning, making the test script invalid.
+func (es ValidationErrors) Failed() bool {
+	for _, e := range es {
+		if !e.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// testScriptValidator collects the ValidationErrors of a test script file.
+// The config of an external config file provides targets and environments missing from the test script.
+// A target set by artillery run options makes targets optional.
+type testScriptValidator struct {
+	path     string
+	external *yaml3.Node
+	target   string
+	errors   ValidationErrors
+}
+
+// ValidateTestScript validates a test script file against the Artillery test script schema,
+// also checking the environments expected to be run are defined.
+// Returns ValidationErrors for an invalid test script, or holding only warnings, see: ValidationErrors.Failed.
+// Returns other errors when the file cannot be read.
+func ValidateTestScript(path string, environments ...string) error {
+	return ValidateTestScriptWithConfig(path, "", environments...)
+}
+
+// ValidateTestScriptWithConfig validates a test script file run with an external config file, see: artillery run --config.
+// The external config file provides the target and environments when the test script does not.
+func ValidateTestScriptWithConfig(path, configPath string, environments ...string) error {
+	return validateTestScript(&testScriptValidator{path: path}, configPath, environments)
+}
+
+// ValidateTestScriptRun validates a test script file run with an external config file and artillery run options.
+// The environment run must be defined, and a target set by the run options makes targets optional.
+func ValidateTestScriptRun(path, configPath string, run RunOptions) error {
+	var environments []string
+	if len(run.Environment) > 0 {
+		environments = append(environments, run.Environment)
+	}
+	return validateTestScript(&testScriptValidator{path: path, target: run.target()}, configPath, environments)
+}
+
+// validateTestScript validates a test script file using a testScriptValidator.
+func validateTestScript(v *testScriptValidator, configPath string, environments []string) error {
+	path := v.path
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return err
+	}
+
+	if len(configPath) > 0 {
+		configData, err := os.ReadFile(filepath.Clean(configPath))
+		if err != nil {
+			return err
+		}
+
+		var configDoc yaml3.Node
+		if err := yaml3.Unmarshal(configData, &configDoc); err != nil {
+			return fmt.Errorf("%s: %w", configPath, err)
+		}
+		v.external = mappingValue(&configDoc, "config")
+	}
+
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(data, &doc); err != nil {
+		v.addYAMLError(&doc, err)
+		return v.errors
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml3.MappingNode {
+		v.add(&doc, "test script must be a mapping with config and scenarios")
+		return v.errors
+	}
+	root := doc.Content[0]
+
+	var t TestScript
+	if err := root.Decode(&t); err != nil {
+		v.addYAMLError(root, err)
+	}
+
+	v.validateRoot(root)
+	v.validateConfig(root, environments)
+	v.validateScenarios(root)
+
+	if len(v.errors) > 0 {
+		sort.SliceStable(v.errors, func(i, j int) bool {
+			if v.errors[i].Line == v.errors[j].Line {
+				return v.errors[i].Column < v.errors[j].Column
+			}
+			return v.errors[i].Line < v.errors[j].Line
+		})
+		return v.errors
+	}
+	return nil
+}
+
+// validateRoot checks the test script has scenarios and only known top level sections.
+func (v *testScriptValidator) validateRoot(root *yaml3.Node) {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if key := root.Content[i]; !topLevelKeys[key.Value] {
+			v.warn(key, fmt.Sprintf("unknown section %q is ignored, expected config, scenarios, before or after", key.Value))
+		}
+	}
+
+	if mappingValue(root, "config") == nil {
+		v.add(root, "config is missing")
+	}
+}
+
+// validateConfig checks the config has a target, valid phases and the expected environments.
+func (v *testScriptValidator) validateConfig(root *yaml3.Node, expected []string) {
+	configKey, config := mappingEntry(root, "config")
+	if config == nil || config.Kind != yaml3.MappingNode {
+		return
+	}
+
+	hasTarget := len(v.target) > 0 || nonEmptyScalar(mappingValue(config, "target")) ||
+		nonEmptyScalar(mappingValue(v.external, "target"))
+	v.validatePhases(mappingValue(config, "phases"))
+
+	externalEnvironments := mappingValue(v.external, "environments")
+	environmentsKey, environments := mappingEntry(config, "environments")
+	if environments != nil && environments.Kind == yaml3.MappingNode {
+		for i := 0; i+1 < len(environments.Content); i += 2 {
+			name, env := environments.Content[i], environments.Content[i+1]
+			v.validatePhases(mappingValue(env, "phases"))
+			if !hasTarget && !nonEmptyScalar(mappingValue(env, "target")) &&
+				!nonEmptyScalar(mappingValue(mappingValue(externalEnvironments, name.Value), "target")) {
+				v.add(name, fmt.Sprintf("environment %q has no target and config.target is missing", name.Value))
+			}
+		}
+	}
+
+	if !hasTarget && len(mappingKeys(environments)) == 0 && len(mappingKeys(externalEnvironments)) == 0 {
+		v.add(configKey, "config.target is missing")
+	}
+
+	for _, name := range expected {
+		if mappingValue(environments, name) != nil || mappingValue(externalEnvironments, name) != nil {
+			continue
+		}
+		at := configKey
+		if environmentsKey != nil {
+			at = environmentsKey
+		}
+		defined := "no environments are defined"
+		if keys := append(mappingKeys(environments), mappingKeys(externalEnvironments)...); len(keys) > 0 {
+			defined = "defined environments are " + strings.Join(keys, ", ")
+		}
+		v.add(at, fmt.Sprintf("unknown environment %q, %s", name, defined))
+	}
+}
+
+// validatePhases checks every phase is a pause, or has a duration and arrival settings.
+func (v *testScriptValidator) validatePhases(phases *yaml3.Node) {
+	if phases == nil || phases.Kind != yaml3.SequenceNode {
+		return
+	}
+
+	for _, phase := range phases.Content {
+		if phase.Kind != yaml3.MappingNode || mappingValue(phase, "pause") != nil {
+			continue
+		}
+
+		if mappingValue(phase, "duration") == nil {
+			v.add(phase, "phase has neither a duration nor a pause, arrival phases spread arrivals over a duration")
+			continue
+		}
+
+		if mappingValue(phase, "arrivalRate") == nil && mappingValue(phase, "arrivalCount") == nil && mappingValue(phase, "rampTo") == nil {
+			v.add(phase, "phase has no arrival settings, set arrivalRate, arrivalCount or rampTo")
+		}
+	}
+}
+
+// validateScenarios checks there are scenarios with a configured engine, each built-in engine scenario with a flow.
+// Scenarios of other engines, e.g. grpc, may define their steps outside a flow.
+func (v *testScriptValidator) validateScenarios(root *yaml3.Node) {
+	scenariosKey, scenarios := mappingEntry(root, "scenarios")
+	if scenarios == nil {
+		v.add(root, "scenarios are missing")
+		return
+	}
+	if scenarios.Kind != yaml3.SequenceNode || len(scenarios.Content) == 0 {
+		v.add(scenariosKey, "scenarios must be a list of at least one scenario")
+		return
+	}
+
+	engines := mappingValue(mappingValue(root, "config"), "engines")
+	for _, scenario := range scenarios.Content {
+		if scenario.Kind != yaml3.MappingNode {
+			continue
+		}
+
+		var engine string
+		if engineKey, engineNode := mappingEntry(scenario, "engine"); engineNode != nil {
+			engine = engineNode.Value
+			if !builtinEngines[engine] && mappingValue(engines, engine) == nil {
+				v.add(engineKey, fmt.Sprintf("engine %q is not configured in config.engines", engine))
+			}
+		}
+
+		flow := mappingValue(scenario, "flow")
+		if flow != nil && flow.Kind == yaml3.SequenceNode && len(flow.Content) > 0 {
+			v.validateFlow(flow)
+		} else if builtinEngines[engine] {
+			v.add(scenario, "scenario has no flow")
+		}
+	}
+}
+
+// validateFlow checks every request in a flow, including requests in loops, has a url.
+func (v *testScriptValidator) validateFlow(flow *yaml3.Node) {
+	for _, step := range flow.Content {
+		if loop := mappingValue(step, "loop"); loop != nil && loop.Kind == yaml3.SequenceNode {
+			v.validateFlow(loop)
+		}
+
+		method, req := request(step)
+		if req != nil && !nonEmptyScalar(mappingValue(req, "url")) {
+			v.add(req, fmt.Sprintf("%s request has no url", method))
+		}
+	}
+}
+
+// add adds a ValidationError at a node's position.
+func (v *testScriptValidator) add(at *yaml3.Node, msg string) {
+	v.errors = append(v.errors, ValidationError{
+		Path:    v.path,
+		Line:    maxInt(at.Line, 1),
+		Column:  maxInt(at.Column, 1),
+		Message: msg,
+	})
+}
+
+// warn adds a warning ValidationError at a node's position.
+func (v *testScriptValidator) warn(at *yaml3.Node, msg string) {
+	v.add(at, msg)
+	v.errors[len(v.errors)-1].Warning = true
+}
+
+// addYAMLError adds ValidationErrors for YAML syntax and type errors, positioned at their line.
+func (v *testScriptValidator) addYAMLError(root *yaml3.Node, err error) {
+	msgs := []string{err.Error()}
+	var typeErr *yaml3.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	}
+
+	for _, msg := range msgs {
+		line := 1
+		if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+			line, _ = strconv.Atoi(m[1])
+			msg = msg[strings.Index(msg, m[0])+len(m[0]):]
+		}
+		v.errors = append(v.errors, ValidationError{
+			Path:    v.path,
+			Line:    line,
+			Column:  columnAt(root, line),
+			Message: strings.TrimPrefix(msg, "yaml: "),
+		})
+	}
+}
+
+// columnAt returns the column of the first node on a line, or 1 when there is none.
+func columnAt(node *yaml3.Node, line int) int {
+	if node.Line == line && node.Kind != yaml3.DocumentNode {
+		return maxInt(node.Column, 1)
+	}
+	for _, child := range node.Content {
+		if col := columnAt(child, line); col > 1 || child.Line == line {
+			return col
+		}
+	}
+	return 1
+}
+
+// mappingEntry returns the key and value nodes of a mapping node's key, or nils when missing.
+func mappingEntry(node *yaml3.Node, key string) (*yaml3.Node, *yaml3.Node) {
+	if node == nil || node.Kind != yaml3.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// mappingKeys returns the keys of a mapping node.
+func mappingKeys(node *yaml3.Node) []string {
+	var keys []string
+	for _, key := range mappingKeyNodes(node) {
+		keys = append(keys, key.Value)
+	}
+	return keys
+}
+
+// nonEmptyScalar checks whether a node is a scalar with a value.
+func nonEmptyScalar(node *yaml3.Node) bool {
+	return node != nil && node.Kind == yaml3.ScalarNode && len(node.Value) > 0
+}
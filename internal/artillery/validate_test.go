@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artilleryio/kubectl-artillery/internal/kube"
+)
+
+func TestValidateTestScriptPhases(t *testing.T) {
+	tests := []struct {
+		name    string
+		phases  string
+		wantErr string
+	}{
+		{name: "arrival rate", phases: "- {duration: 60, arrivalRate: 5}"},
+		{name: "arrival count", phases: "- {duration: 1, arrivalCount: 1}"},
+		{name: "pause", phases: "- {pause: 10}"},
+		{
+			name:    "arrival count without duration",
+			phases:  "- {arrivalCount: 10}",
+			wantErr: "4:7: phase has neither a duration nor a pause",
+		},
+		{
+			name:    "no arrivals",
+			phases:  "- {duration: 60}",
+			wantErr: "4:7: phase has no arrival settings",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test-script.yaml")
+			script := "config:\n  target: http://orders\n  phases:\n    " + tt.phases + "\nscenarios:\n  - flow:\n      - get: {url: /}\n"
+			if err := os.WriteFile(path, []byte(script), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ValidateTestScript(path)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateTestScript() error = %v", err)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateTestScript() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateStreamingTestScript(t *testing.T) {
+	for _, port := range []kube.ServicePort{
+		{Name: "grpc", Url: &url.URL{Scheme: "http", Host: "orders:9090"}, Protocol: kube.ProtocolGRPC},
+		{Name: "ws", Url: &url.URL{Scheme: "ws", Host: "orders:8080"}, Protocol: kube.ProtocolWS},
+		{Name: "socketio", Url: &url.URL{Scheme: "http", Host: "orders:3000"}, Protocol: kube.ProtocolSocketIO},
+	} {
+		t.Run(port.Name, func(t *testing.T) {
+			data, err := NewStreamingTestScript("orders", port).MarshalWithIndent(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "test-script.yaml")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ValidateTestScript(path); err != nil {
+				t.Errorf("ValidateTestScript() error = %v, script:\n%s", err, data)
+			}
+		})
+	}
+}
+
+func TestValidateTestScriptScenarios(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    string
+		wantErr   string
+		wantFails bool
+	}{
+		{
+			name:      "http scenario without flow",
+			script:    "config:\n  target: http://orders\nscenarios:\n  - name: orders\n",
+			wantErr:   "4:5: scenario has no flow",
+			wantFails: true,
+		},
+		{
+			name:   "custom engine scenario without flow",
+			script: "config:\n  target: orders:9090\n  engines: {grpc: {}}\nscenarios:\n  - engine: grpc\n",
+		},
+		{
+			name:    "unknown section",
+			script:  "config:\n  target: http://orders\nscenarios:\n  - flow:\n      - get: {url: /}\nscenario: []\n",
+			wantErr: `6:1: warning: unknown section "scenario" is ignored`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test-script.yaml")
+			if err := os.WriteFile(path, []byte(tt.script), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ValidateTestScript(path)
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateTestScript() error = %v", err)
+				}
+				return
+			}
+
+			var verrs ValidationErrors
+			if !errors.As(err, &verrs) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateTestScript() error = %v, want %q", err, tt.wantErr)
+			}
+			if verrs.Failed() != tt.wantFails {
+				t.Errorf("Failed() = %v, want %v", verrs.Failed(), tt.wantFails)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"fmt"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// IntOrString a test script number that may also be a string, e.g. a template "{{ rate }}" or a duration "5m".
+// Non-int values keep their YAML tag, so floats such as think times of 0.5 round-trip as floats.
+// Set values are kept even when 0, e.g. arrivalRate: 0 ramping up to a rate.
+type IntOrString struct {
+	IntVal int
+	StrVal string
+	tag    string
+	set    bool
+}
+
+// FromInt returns an IntOrString holding an int.
+func FromInt(i int) IntOrString {
+	return IntOrString{IntVal: i, set: true}
+}
+
+// FromString returns an IntOrString holding a string.
+func FromString(s string) IntOrString {
+	return IntOrString{StrVal: s, tag: "!!str", set: true}
+}
+
+// IsString checks whether an IntOrString holds a string, or another non-int value such as a float.
+func (v IntOrString) IsString() bool {
+	return len(v.tag) > 0
+}
+
+// IsZero checks whether an IntOrString is unset, used to omit empty values.
+func (v IntOrString) IsZero() bool {
+	return !v.set
+}
+
+// String returns an IntOrString's value as it appears in a test script.
+func (v IntOrString) String() string {
+	if v.IsString() {
+		return v.StrVal
+	}
+	return fmt.Sprintf("%d", v.IntVal)
+}
+
+// MarshalYAML marshals an IntOrString as an int, or a scalar with its original tag.
+func (v IntOrString) MarshalYAML() (interface{}, error) {
+	if !v.IsString() {
+		return v.IntVal, nil
+	}
+	return &yaml3.Node{Kind: yaml3.ScalarNode, Tag: v.tag, Value: v.StrVal}, nil
+}
+
+// UnmarshalYAML unmarshals an IntOrString from any scalar.
+func (v *IntOrString) UnmarshalYAML(value *yaml3.Node) error {
+	if value.Kind != yaml3.ScalarNode {
+		return fmt.Errorf("line %d: expected a number or a string", value.Line)
+	}
+
+	if value.ShortTag() == "!!int" {
+		*v = IntOrString{set: true}
+		return value.Decode(&v.IntVal)
+	}
+
+	*v = IntOrString{StrVal: value.Value, tag: value.ShortTag(), set: true}
+	return nil
+}
+
+// Functions processor function names, written either as a single name or a list of names.
+// Parsed Functions keep their form.
+type Functions struct {
+	Names  []string
+	single bool
+}
+
+// IsZero checks whether Functions has no names, used to omit empty values.
+func (f Functions) IsZero() bool {
+	return len(f.Names) == 0
+}
+
+// MarshalYAML marshals Functions as a single name when parsed from one, otherwise as a list.
+func (f Functions) MarshalYAML() (interface{}, error) {
+	if f.single && len(f.Names) == 1 {
+		return f.Names[0], nil
+	}
+	return f.Names, nil
+}
+
+// UnmarshalYAML unmarshals Functions from a single name or a list of names.
+func (f *Functions) UnmarshalYAML(value *yaml3.Node) error {
+	if value.Kind == yaml3.ScalarNode {
+		*f = Functions{Names: []string{value.Value}, single: true}
+		return nil
+	}
+
+	*f = Functions{}
+	return value.Decode(&f.Names)
+}
+
+// Captures request captures or matches, written either as a single capture or a list of captures.
+// Parsed Captures keep their form.
+type Captures struct {
+	Items  []Capture
+	single bool
+}
+
+// IsZero checks whether Captures has no captures, used to omit empty values.
+func (c Captures) IsZero() bool {
+	return len(c.Items) == 0
+}
+
+// MarshalYAML marshals Captures as a single capture when parsed from one, otherwise as a list.
+func (c Captures) MarshalYAML() (interface{}, error) {
+	if c.single && len(c.Items) == 1 {
+		return c.Items[0], nil
+	}
+	return c.Items, nil
+}
+
+// UnmarshalYAML unmarshals Captures from a single capture or a list of captures.
+func (c *Captures) UnmarshalYAML(value *yaml3.Node) error {
+	*c = Captures{single: value.Kind == yaml3.MappingNode}
+	if c.single {
+		c.Items = make([]Capture, 1)
+		return value.Decode(&c.Items[0])
+	}
+	return value.Decode(&c.Items)
+}
+
+// Payloads test script payload files, written either as a single payload or a list of payloads.
+// Parsed Payloads keep their form.
+type Payloads struct {
+	Items  []Payload
+	single bool
+}
+
+// IsZero checks whether Payloads has no payloads, used to omit empty values.
+func (p Payloads) IsZero() bool {
+	return len(p.Items) == 0
+}
+
+// MarshalYAML marshals Payloads as a single payload when parsed from one, otherwise as a list.
+func (p Payloads) MarshalYAML() (interface{}, error) {
+	if p.single && len(p.Items) == 1 {
+		return p.Items[0], nil
+	}
+	return p.Items, nil
+}
+
+// UnmarshalYAML unmarshals Payloads from a single payload or a list of payloads.
+func (p *Payloads) UnmarshalYAML(value *yaml3.Node) error {
+	*p = Payloads{single: value.Kind == yaml3.MappingNode}
+	if p.single {
+		p.Items = make([]Payload, 1)
+		return value.Decode(&p.Items[0])
+	}
+	return value.Decode(&p.Items)
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package artillery
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+func TestTestScriptRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name: "explicit zeros",
+			script: `
+config:
+  target: http://orders
+  phases:
+    - duration: 60
+      arrivalRate: 0
+      rampTo: 10
+    - pause: 0
+scenarios:
+  - name: disabled
+    weight: 0
+    flow:
+      - think: 0
+      - loop:
+          - get: {url: /}
+        count: 0
+`,
+		},
+		{
+			name: "single values",
+			script: `
+config:
+  target: http://orders
+  payload:
+    path: users.csv
+    fields: [id]
+  processor: ./functions.js
+scenarios:
+  - beforeScenario: setUp
+    flow:
+      - post:
+          url: /login
+          beforeRequest: sign
+          afterResponse: [log, check]
+          capture:
+            json: $.token
+            as: token
+          match:
+            - json: $.ok
+              value: true
+`,
+		},
+		{
+			name: "unknown keys",
+			script: `
+config:
+  target: http://orders
+  custom: {enabled: true}
+  tls: {rejectUnauthorized: false, ca: ca.pem}
+scenarios:
+  - engine: socketio
+    flow:
+      - emit: [join, {room: 1}]
+      - emit: [ping]
+      - get:
+          url: /
+          auth: {user: u, pass: p, sendImmediately: true}
+      - think: 0.5
+      - grpcCall: {method: Get}
+custom: kept
+`,
+		},
+		{
+			name: "untyped request values",
+			script: `
+config:
+  target: http://orders
+scenarios:
+  - flow:
+      - post:
+          url: /orders
+          headers: {X-N: 5, X-Trace: true}
+          cookie: {session: 1}
+          body: 5
+          auth: {user: u}
+`,
+		},
+		{
+			name: "explicit nulls",
+			script: `
+config:
+  target: http://orders
+  phases:
+    - name: null
+      duration: 60
+      arrivalRate: 1
+  environments:
+    staging:
+      processor: null
+      plugins: {expect: null}
+scenarios:
+  - name: null
+    flow:
+      - post:
+          url: /orders
+          json: null
+          headers: {X-Empty: null}
+      - emit: {channel: join, data: null}
+    custom: null
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, err := ParseTestScript([]byte(tt.script))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := ts.MarshalWithIndent(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var want, got interface{}
+			if err := yaml3.Unmarshal([]byte(tt.script), &want); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml3.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("round trip changed the test script, got:\n%s", data)
+			}
+		})
+	}
+}
+
+func TestIntOrStringIsZero(t *testing.T) {
+	if !(IntOrString{}).IsZero() {
+		t.Error("unset IntOrString is not zero")
+	}
+	if FromInt(0).IsZero() {
+		t.Error("FromInt(0) is zero, want set")
+	}
+}
+
+func TestPayloadWithoutPath(t *testing.T) {
+	data, err := yaml3.Marshal(Payload{Fields: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "path") {
+		t.Errorf("payload without path marshals a path:\n%s", data)
+	}
+}
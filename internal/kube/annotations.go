@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EndpointsAnnotation lists the comma separated test endpoints a Service declares,
+	// each as <METHOD> <path> [expected status code].
+	// e.g. artillery.io/endpoints: "GET /api/orders 200, POST /api/orders 201"
+	EndpointsAnnotation = "artillery.io/endpoints"
+
+	// HeadersAnnotation lists the comma separated headers sent to a Service's declared test endpoints.
+	// e.g. artillery.io/headers: "X-Tenant: acme, Accept: application/json"
+	HeadersAnnotation = "artillery.io/headers"
+)
+
+// AnnotatedEndpoints is a list of test endpoints declared using K8s Service annotations.
+type AnnotatedEndpoints struct {
+	Url       *url.URL
+	Headers   map[string]string
+	Endpoints []ServiceEndpoint
+}
+
+// ServiceEndpoint a K8s Service's test endpoint.
+type ServiceEndpoint struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+// HasAnnotatedEndpoints returns whether a query result found a K8s Service declaring test endpoints.
+func (qr QueryResult) HasAnnotatedEndpoints() bool {
+	_, found := qr.selection.Service.Annotations[EndpointsAnnotation]
+	return found
+}
+
+// AnnotatedEndpoints returns the test endpoints a query result's K8s Service declares using annotations.
+func (qr QueryResult) AnnotatedEndpoints() (*AnnotatedEndpoints, error) {
+	return qr.selection.AnnotatedEndpoints()
+}
+
+// AnnotatedEndpoints returns the test endpoints a Service declares using the EndpointsAnnotation,
+// along with headers declared using the HeadersAnnotation.
+// Endpoints target the Service's first port.
+// It returns nil when the Service declares no test endpoints.
+func (s Selection) AnnotatedEndpoints() (*AnnotatedEndpoints, error) {
+	declared := annotationList(s.Service.Annotations[EndpointsAnnotation])
+	if len(declared) == 0 || len(s.Service.Spec.Ports) == 0 {
+		return nil, nil
+	}
+
+	var endpoints []ServiceEndpoint
+	for _, d := range declared {
+		endpoint, err := parseServiceEndpoint(d)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", EndpointsAnnotation, err)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	headers := map[string]string{}
+	for _, h := range annotationList(s.Service.Annotations[HeadersAnnotation]) {
+		name, value, found := strings.Cut(h, ":")
+		if !found || len(strings.TrimSpace(name)) == 0 {
+			return nil, fmt.Errorf("invalid %s annotation: %q is not a <name>: <value> header", HeadersAnnotation, h)
+		}
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	return &AnnotatedEndpoints{
+		Url:       s.ServiceUrl(),
+		Headers:   headers,
+		Endpoints: endpoints,
+	}, nil
+}
+
+// parseServiceEndpoint parses a <METHOD> <path> [expected status code] declared endpoint.
+// The expected status code defaults to 200.
+func parseServiceEndpoint(declared string) (ServiceEndpoint, error) {
+	fields := strings.Fields(declared)
+	if len(fields) < 2 || len(fields) > 3 {
+		return ServiceEndpoint{}, fmt.Errorf("%q is not a <METHOD> <path> [status code] endpoint", declared)
+	}
+
+	endpoint := ServiceEndpoint{
+		Method:     strings.ToUpper(fields[0]),
+		Path:       fields[1],
+		StatusCode: http.StatusOK,
+	}
+
+	switch endpoint.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions:
+	default:
+		return ServiceEndpoint{}, fmt.Errorf("%q has unsupported method %s", declared, fields[0])
+	}
+
+	if !strings.HasPrefix(endpoint.Path, "/") {
+		return ServiceEndpoint{}, fmt.Errorf("%q has path %s not starting with /", declared, endpoint.Path)
+	}
+
+	if len(fields) == 3 {
+		code, err := strconv.Atoi(fields[2])
+		if err != nil || code < 100 || code > 599 {
+			return ServiceEndpoint{}, fmt.Errorf("%q has invalid status code %s", declared, fields[2])
+		}
+		endpoint.StatusCode = code
+	}
+
+	return endpoint, nil
+}
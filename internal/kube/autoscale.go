@@ -0,0 +1,214 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// HorizontalPodAutoscalerResource the autoscaling/v2 HorizontalPodAutoscaler API resource, served since K8s 1.23.
+// Its schema matches autoscaling/v2beta2, removed in K8s 1.26.
+var HorizontalPodAutoscalerResource = schema.GroupVersionResource{
+	Group:    "autoscaling",
+	Version:  "v2",
+	Resource: "horizontalpodautoscalers",
+}
+
+// Autoscaling defines the HorizontalPodAutoscaler settings of a K8s Service's backing workload.
+type Autoscaling struct {
+	WorkloadKind string
+	WorkloadName string
+	MinReplicas  int32
+	MaxReplicas  int32
+	// Targets human-readable metric targets that trigger scaling, e.g. "cpu 70%"
+	Targets []string
+}
+
+// GetAutoscaling queries a K8s cluster for the HorizontalPodAutoscaler scaling a query result's backing workload.
+// The workload is found by following the selected Pod's controller owners, e.g. Pod -> ReplicaSet -> Deployment.
+// It returns nil when the workload has no HorizontalPodAutoscaler.
+func GetAutoscaling(ctx context.Context, qr QueryResult, ns string, ctl *Client) (*Autoscaling, error) {
+	kind, name, err := workload(ctx, qr.selection.Pod.ObjectMeta, ns, ctl)
+	if err != nil || len(kind) == 0 {
+		return nil, err
+	}
+
+	hpas, err := horizontalPodAutoscalers(ctx, ns, ctl)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hpa := range hpas {
+		if hpa.Spec.ScaleTargetRef.Kind != kind || hpa.Spec.ScaleTargetRef.Name != name {
+			continue
+		}
+
+		minReplicas := int32(1)
+		if hpa.Spec.MinReplicas != nil {
+			minReplicas = *hpa.Spec.MinReplicas
+		}
+
+		return &Autoscaling{
+			WorkloadKind: kind,
+			WorkloadName: name,
+			MinReplicas:  minReplicas,
+			MaxReplicas:  hpa.Spec.MaxReplicas,
+			Targets:      metricTargets(hpa.Spec.Metrics),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+// horizontalPodAutoscalers lists the HorizontalPodAutoscalers of a namespace using autoscaling/v2,
+// falling back to autoscaling/v1 on clusters not serving v2, which only scale on CPU utilization.
+func horizontalPodAutoscalers(ctx context.Context, ns string, ctl *Client) ([]autoscalingv2beta2.HorizontalPodAutoscaler, error) {
+	list, err := ctl.Dynamic.Resource(HorizontalPodAutoscalerResource).Namespace(ns).List(ctx, metav1.ListOptions{})
+	if err == nil {
+		out := make([]autoscalingv2beta2.HorizontalPodAutoscaler, len(list.Items))
+		for i, item := range list.Items {
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, &out[i]); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	v1List, err := ctl.AutoscalingV1().HorizontalPodAutoscalers(ns).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var out []autoscalingv2beta2.HorizontalPodAutoscaler
+	for _, hpa := range v1List.Items {
+		out = append(out, fromV1(hpa))
+	}
+	return out, nil
+}
+
+// fromV1 converts an autoscaling/v1 HorizontalPodAutoscaler, its CPU utilization target becomes a resource metric.
+func fromV1(hpa autoscalingv1.HorizontalPodAutoscaler) autoscalingv2beta2.HorizontalPodAutoscaler {
+	out := autoscalingv2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: hpa.ObjectMeta,
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+				Kind:       hpa.Spec.ScaleTargetRef.Kind,
+				Name:       hpa.Spec.ScaleTargetRef.Name,
+				APIVersion: hpa.Spec.ScaleTargetRef.APIVersion,
+			},
+			MinReplicas: hpa.Spec.MinReplicas,
+			MaxReplicas: hpa.Spec.MaxReplicas,
+		},
+	}
+
+	if hpa.Spec.TargetCPUUtilizationPercentage != nil {
+		out.Spec.Metrics = []autoscalingv2beta2.MetricSpec{
+			{
+				Type: autoscalingv2beta2.ResourceMetricSourceType,
+				Resource: &autoscalingv2beta2.ResourceMetricSource{
+					Name: corev1.ResourceCPU,
+					Target: autoscalingv2beta2.MetricTarget{
+						Type:               autoscalingv2beta2.UtilizationMetricType,
+						AverageUtilization: hpa.Spec.TargetCPUUtilizationPercentage,
+					},
+				},
+			},
+		}
+	}
+	return out
+}
+
+// workload returns the kind and name of the workload controlling a Pod.
+// Pods controlled by a ReplicaSet resolve to the ReplicaSet's Deployment, if any.
+func workload(ctx context.Context, pod metav1.ObjectMeta, ns string, ctl *Client) (string, string, error) {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil {
+		return "", "", nil
+	}
+
+	if owner.Kind != "ReplicaSet" {
+		return owner.Kind, owner.Name, nil
+	}
+
+	rs, err := ctl.AppsV1().ReplicaSets(ns).Get(ctx, owner.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return owner.Kind, owner.Name, nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if rsOwner := metav1.GetControllerOf(rs); rsOwner != nil {
+		return rsOwner.Kind, rsOwner.Name, nil
+	}
+
+	return owner.Kind, owner.Name, nil
+}
+
+// metricTargets returns human-readable HorizontalPodAutoscaler metric targets.
+func metricTargets(metrics []autoscalingv2beta2.MetricSpec) []string {
+	var out []string
+	for _, metric := range metrics {
+		switch metric.Type {
+		case autoscalingv2beta2.ResourceMetricSourceType:
+			if metric.Resource != nil {
+				out = append(out, fmt.Sprintf("%s %s", metric.Resource.Name, targetValue(metric.Resource.Target)))
+			}
+		case autoscalingv2beta2.ContainerResourceMetricSourceType:
+			if metric.ContainerResource != nil {
+				out = append(out, fmt.Sprintf("%s %s", metric.ContainerResource.Name, targetValue(metric.ContainerResource.Target)))
+			}
+		case autoscalingv2beta2.PodsMetricSourceType:
+			if metric.Pods != nil {
+				out = append(out, fmt.Sprintf("%s %s", metric.Pods.Metric.Name, targetValue(metric.Pods.Target)))
+			}
+		case autoscalingv2beta2.ObjectMetricSourceType:
+			if metric.Object != nil {
+				out = append(out, fmt.Sprintf("%s %s", metric.Object.Metric.Name, targetValue(metric.Object.Target)))
+			}
+		case autoscalingv2beta2.ExternalMetricSourceType:
+			if metric.External != nil {
+				out = append(out, fmt.Sprintf("%s %s", metric.External.Metric.Name, targetValue(metric.External.Target)))
+			}
+		}
+	}
+	return out
+}
+
+// targetValue returns a human-readable HorizontalPodAutoscaler metric target value.
+func targetValue(target autoscalingv2beta2.MetricTarget) string {
+	switch {
+	case target.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *target.AverageUtilization)
+	case target.AverageValue != nil:
+		return target.AverageValue.String()
+	case target.Value != nil:
+		return target.Value.String()
+	}
+	return ""
+}
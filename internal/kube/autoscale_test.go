@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"reflect"
+	"testing"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+)
+
+func TestFromV1(t *testing.T) {
+	minReplicas, cpu := int32(2), int32(70)
+	hpa := fromV1(autoscalingv1.HorizontalPodAutoscaler{
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef:                 autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+			MinReplicas:                    &minReplicas,
+			MaxReplicas:                    10,
+			TargetCPUUtilizationPercentage: &cpu,
+		},
+	})
+
+	if hpa.Spec.ScaleTargetRef.Kind != "Deployment" || hpa.Spec.ScaleTargetRef.Name != "web" {
+		t.Errorf("scale target = %v, want Deployment web", hpa.Spec.ScaleTargetRef)
+	}
+	if *hpa.Spec.MinReplicas != 2 || hpa.Spec.MaxReplicas != 10 {
+		t.Errorf("replicas = %d-%d, want 2-10", *hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas)
+	}
+	if got, want := metricTargets(hpa.Spec.Metrics), []string{"cpu 70%"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("targets = %v, want %v", got, want)
+	}
+
+	if targets := metricTargets(fromV1(autoscalingv1.HorizontalPodAutoscaler{}).Spec.Metrics); len(targets) != 0 {
+		t.Errorf("targets without CPU target = %v, want none", targets)
+	}
+}
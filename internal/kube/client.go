@@ -14,12 +14,15 @@ package kube
 
 import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 )
 
 // Client defines the K8s client.
+// Dynamic is used to access custom resources, e.g. Knative Services.
 type Client struct {
 	CfgNamespace string
+	Dynamic      dynamic.Interface
 	*kubernetes.Clientset
 }
 
@@ -43,8 +46,14 @@ func NewClient(configFlags *genericclioptions.ConfigFlags) (*Client, error) {
 		return nil, err
 	}
 
+	dynamicCtl, err := dynamic.NewForConfig(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Client{
 		CfgNamespace: cfgNamespace,
+		Dynamic:      dynamicCtl,
 		Clientset:    ctl,
 	}, nil
 }
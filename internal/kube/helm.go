@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// helmRelease defines the parts of a Helm release stored in a sh.helm.release.v1.* Secret used to scaffold.
+type helmRelease struct {
+	Name     string `json:"name"`
+	Manifest string `json:"manifest"`
+}
+
+// GetHelmReleaseManifests queries a K8s cluster for a Helm release's rendered manifests using
+// a specified release name and namespace.
+// Manifests are read from the latest deployed revision's sh.helm.release.v1.* Secret.
+// It returns nil when the Helm release cannot be found.
+func GetHelmReleaseManifests(ctx context.Context, name, ns string, ctl *Client) (*Manifests, error) {
+	secrets, err := ctl.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("owner=helm,name=%s", name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	secret := latestHelmRevision(secrets.Items)
+	if secret == nil {
+		return nil, nil
+	}
+
+	release, err := decodeHelmRelease(secret.Data["release"])
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode helm release %s from secret %s: %w", name, secret.Name, err)
+	}
+
+	m := &Manifests{}
+	return m, m.Read(strings.NewReader(release.Manifest))
+}
+
+// latestHelmRevision returns the Secret storing a Helm release's latest deployed revision.
+// Defaults to the latest revision when no revision is deployed.
+func latestHelmRevision(secrets []corev1.Secret) *corev1.Secret {
+	var latest, latestDeployed *corev1.Secret
+	for i := range secrets {
+		secret := &secrets[i]
+		if !strings.HasPrefix(string(secret.Type), "helm.sh/release") {
+			continue
+		}
+
+		if latest == nil || helmRevision(secret) > helmRevision(latest) {
+			latest = secret
+		}
+
+		deployed := secret.Labels["status"] == "deployed"
+		if deployed && (latestDeployed == nil || helmRevision(secret) > helmRevision(latestDeployed)) {
+			latestDeployed = secret
+		}
+	}
+
+	if latestDeployed != nil {
+		return latestDeployed
+	}
+	return latest
+}
+
+// helmRevision returns the Helm release revision stored in a Secret.
+func helmRevision(secret *corev1.Secret) int {
+	version, _ := strconv.Atoi(secret.Labels["version"])
+	return version
+}
+
+// decodeHelmRelease decodes a Helm release, stored as base64 encoded gzipped JSON.
+func decodeHelmRelease(data []byte) (*helmRelease, error) {
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// Helm releases are only gzipped when the gzip magic header is present.
+	if bytes.HasPrefix(decoded, []byte{0x1f, 0x8b, 0x08}) {
+		reader, err := gzip.NewReader(bytes.NewReader(decoded))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+
+		decoded, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var release helmRelease
+	if err := json.Unmarshal(decoded, &release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// KafkaTopicResource the Strimzi KafkaTopic API resource.
+	KafkaTopicResource = schema.GroupVersionResource{
+		Group:    "kafka.strimzi.io",
+		Version:  "v1beta2",
+		Resource: "kafkatopics",
+	}
+
+	// KafkaResource the Strimzi Kafka cluster API resource.
+	KafkaResource = schema.GroupVersionResource{
+		Group:    "kafka.strimzi.io",
+		Version:  "v1beta2",
+		Resource: "kafkas",
+	}
+)
+
+// strimziClusterLabel labels Strimzi resources with their Kafka cluster name.
+const strimziClusterLabel = "strimzi.io/cluster"
+
+// KafkaTopic defines the test relevant settings of a Strimzi KafkaTopic.
+type KafkaTopic struct {
+	// Name the KafkaTopic resource name.
+	Name string
+	// TopicName the Kafka topic name, defaults to the resource name.
+	TopicName string
+	// Cluster the Strimzi Kafka cluster name.
+	Cluster string
+	// BootstrapServers comma separated Kafka bootstrap servers, e.g. my-cluster-kafka-bootstrap:9092
+	BootstrapServers string
+}
+
+// GetKafkaTopic queries a K8s cluster for a Strimzi KafkaTopic using a specified name and namespace,
+// resolving the bootstrap servers of the topic's Kafka cluster.
+// It returns nil when the KafkaTopic cannot be found.
+func GetKafkaTopic(ctx context.Context, name, ns string, ctl *Client) (*KafkaTopic, error) {
+	obj, err := ctl.Dynamic.Resource(KafkaTopicResource).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	topic := &KafkaTopic{
+		Name:      obj.GetName(),
+		TopicName: obj.GetName(),
+		Cluster:   obj.GetLabels()[strimziClusterLabel],
+	}
+
+	if topicName, found, _ := unstructured.NestedString(obj.Object, "spec", "topicName"); found && len(topicName) > 0 {
+		topic.TopicName = topicName
+	}
+
+	if len(topic.Cluster) == 0 {
+		return nil, fmt.Errorf("kafkatopic \"%s\" has no %s label", name, strimziClusterLabel)
+	}
+
+	topic.BootstrapServers, err = bootstrapServers(ctx, topic.Cluster, ns, ctl)
+	if err != nil {
+		return nil, err
+	}
+
+	return topic, nil
+}
+
+// bootstrapServers returns a Strimzi Kafka cluster's plain listener bootstrap servers.
+// Bootstrap servers are read from the Kafka resource's status, or else resolved using
+// the cluster's bootstrap Service client port.
+func bootstrapServers(ctx context.Context, cluster, ns string, ctl *Client) (string, error) {
+	kafka, err := ctl.Dynamic.Resource(KafkaResource).Namespace(ns).Get(ctx, cluster, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("kafka \"%s\" not found", cluster)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	listeners, _, _ := unstructured.NestedSlice(kafka.Object, "status", "listeners")
+	for _, l := range listeners {
+		listener, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		name, _, _ := unstructured.NestedString(listener, "name")
+		servers, _, _ := unstructured.NestedString(listener, "bootstrapServers")
+		if name == "plain" && len(servers) > 0 {
+			return servers, nil
+		}
+	}
+
+	svcName := fmt.Sprintf("%s-kafka-bootstrap", cluster)
+	svc, err := ctl.CoreV1().Services(ns).Get(ctx, svcName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve kafka \"%s\" bootstrap service: %w", cluster, err)
+	}
+
+	for _, port := range svc.Spec.Ports {
+		if port.Name == "tcp-clients" {
+			return fmt.Sprintf("%s:%d", svc.Name, port.Port), nil
+		}
+	}
+
+	return "", fmt.Errorf("kafka \"%s\" bootstrap service %s has no plain client port", cluster, svcName)
+}
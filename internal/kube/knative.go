@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"context"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// KnativeServiceResource the Knative Serving Service (ksvc) API resource.
+var KnativeServiceResource = schema.GroupVersionResource{
+	Group:    "serving.knative.dev",
+	Version:  "v1",
+	Resource: "services",
+}
+
+// KnativeService defines the test relevant settings of a Knative Serving Service.
+type KnativeService struct {
+	Name           string
+	Url            *url.URL
+	ReadinessProbe *corev1.Probe
+}
+
+// GetKnativeService queries a K8s cluster for a Knative Service using a specified name and namespace.
+// It returns nil when the Knative Service cannot be found.
+func GetKnativeService(ctx context.Context, name, ns string, ctl *Client) (*KnativeService, error) {
+	obj, err := ctl.Dynamic.Resource(KnativeServiceResource).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &KnativeService{Name: obj.GetName()}
+
+	statusUrl, found, err := unstructured.NestedString(obj.Object, "status", "url")
+	if err != nil {
+		return nil, err
+	}
+
+	if found && len(statusUrl) > 0 {
+		result.Url, err = url.Parse(statusUrl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	containers, found, err := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+	if err != nil || !found || len(containers) == 0 {
+		return result, err
+	}
+
+	raw, ok := containers[0].(map[string]interface{})
+	if !ok {
+		return result, nil
+	}
+
+	var container corev1.Container
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &container); err != nil {
+		return nil, err
+	}
+	result.ReadinessProbe = container.ReadinessProbe
+
+	return result, nil
+}
+
+// IsReady returns whether a Knative Service has been assigned a status url to route traffic.
+func (k KnativeService) IsReady() bool {
+	return k.Url != nil
+}
+
+// ServiceProbes returns a Knative Service's HTTP Get readiness probe targeting its status url.
+// Knative manages probe ports, so only the probe's path is used.
+// Defaults to the root path when the revision template has no HTTP Get readiness probe.
+func (k KnativeService) ServiceProbes() ServiceProbes {
+	var out ServiceProbes
+	if !k.IsReady() {
+		return out
+	}
+
+	httpGet := &corev1.HTTPGetAction{Path: "/"}
+	if k.ReadinessProbe != nil && k.ReadinessProbe.HTTPGet != nil {
+		httpGet = k.ReadinessProbe.HTTPGet
+	}
+
+	return append(out, ServiceProbe{
+		Url:      &url.URL{Scheme: k.Url.Scheme, Host: k.Url.Host},
+		HTTPGets: []*corev1.HTTPGetAction{httpGet},
+	})
+}
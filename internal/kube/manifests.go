@@ -0,0 +1,290 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// Manifests defines K8s objects read from local manifests.
+// Used to scaffold test scripts without cluster access.
+type Manifests struct {
+	Services []corev1.Service
+	// Pods includes standalone Pods and Pods templated by workloads, e.g. Deployments.
+	Pods []corev1.Pod
+	// StatefulSets provide per-Pod hostnames for their governing headless Services.
+	StatefulSets []appsv1.StatefulSet
+}
+
+// ReadFiles reads K8s objects from manifest files, or directories of manifest files.
+// A "-" path reads manifests from stdin, e.g. piped kubectl get -o yaml output.
+func (m *Manifests) ReadFiles(paths []string, stdin io.Reader) error {
+	for _, path := range paths {
+		if path == "-" {
+			if err := m.Read(stdin); err != nil {
+				return fmt.Errorf("cannot read manifests from stdin: %w", err)
+			}
+			continue
+		}
+
+		err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() || !isManifestFile(p) && p != path {
+				return nil
+			}
+
+			file, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			if err := m.Read(file); err != nil {
+				return fmt.Errorf("cannot read manifests from %s: %w", p, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadKustomization reads K8s objects from a kustomization directory, rendered in-process.
+func (m *Manifests) ReadKustomization(dir string) error {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resources, err := k.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return err
+	}
+
+	data, err := resources.AsYaml()
+	if err != nil {
+		return err
+	}
+
+	return m.Read(bytes.NewReader(data))
+}
+
+// isManifestFile returns whether a file is a YAML or JSON manifest file.
+func isManifestFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// Read reads K8s objects from YAML or JSON manifests, including multi-document YAML and Lists.
+// Objects not used to scaffold test scripts are ignored.
+func (m *Manifests) Read(r io.Reader) error {
+	decoder := k8syaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var obj map[string]interface{}
+		err := decoder.Decode(&obj)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := m.add(obj); err != nil {
+			return err
+		}
+	}
+}
+
+// add adds a K8s object to manifests based on its kind.
+func (m *Manifests) add(obj map[string]interface{}) error {
+	if obj == nil {
+		return nil
+	}
+
+	kind, _ := obj["kind"].(string)
+	switch kind {
+	case "List":
+		items, _ := obj["items"].([]interface{})
+		for i, item := range items {
+			itemObj, ok := item.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("List item %d is not an object", i)
+			}
+			if err := m.add(itemObj); err != nil {
+				return err
+			}
+		}
+	case "Service":
+		var svc corev1.Service
+		if err := fromUnstructured(obj, &svc); err != nil {
+			return err
+		}
+
+		// Defaults unset target ports to the Service port, as the K8s API server does.
+		for i, port := range svc.Spec.Ports {
+			if port.TargetPort.IntVal == 0 && len(port.TargetPort.StrVal) == 0 {
+				svc.Spec.Ports[i].TargetPort = intstr.FromInt(int(port.Port))
+			}
+		}
+		m.Services = append(m.Services, svc)
+	case "Pod":
+		var pod corev1.Pod
+		if err := fromUnstructured(obj, &pod); err != nil {
+			return err
+		}
+		m.Pods = append(m.Pods, pod)
+	case "Deployment":
+		var deployment appsv1.Deployment
+		if err := fromUnstructured(obj, &deployment); err != nil {
+			return err
+		}
+		m.Pods = append(m.Pods, templatedPod(deployment.ObjectMeta, deployment.Spec.Template))
+	case "StatefulSet":
+		var sts appsv1.StatefulSet
+		if err := fromUnstructured(obj, &sts); err != nil {
+			return err
+		}
+		m.StatefulSets = append(m.StatefulSets, sts)
+		m.Pods = append(m.Pods, templatedPod(sts.ObjectMeta, sts.Spec.Template))
+	}
+	return nil
+}
+
+// fromUnstructured converts an unstructured K8s object to a typed object.
+func fromUnstructured(obj map[string]interface{}, out interface{}) error {
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, out); err != nil {
+		return fmt.Errorf("cannot read %s %v: %w", obj["kind"], obj["metadata"], err)
+	}
+	return nil
+}
+
+// templatedPod returns a Pod created by a workload's Pod template.
+func templatedPod(workload metav1.ObjectMeta, template corev1.PodTemplateSpec) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: template.ObjectMeta,
+		Spec:       template.Spec,
+	}
+	pod.Namespace = workload.Namespace
+	if len(pod.Name) == 0 {
+		pod.Name = workload.Name
+	}
+	return pod
+}
+
+// ServiceNames returns the names of all manifest Services.
+func (m *Manifests) ServiceNames() []string {
+	var out []string
+	for _, svc := range m.Services {
+		out = append(out, svc.Name)
+	}
+	return out
+}
+
+// DoQuery queries manifests for K8s services using specified service names.
+// All manifest Services are queried when no service names are specified.
+// It returns a list of query results, one for each found and missed service name.
+func (m *Manifests) DoQuery(svcNames []string) QueryResults {
+	if len(svcNames) == 0 {
+		svcNames = m.ServiceNames()
+	}
+
+	var result QueryResults
+	for _, svcName := range svcNames {
+		qr := QueryResult{serviceName: svcName, selection: Selection{}}
+
+		for _, service := range m.Services {
+			if strings.ToLower(service.Name) != strings.ToLower(svcName) {
+				continue
+			}
+
+			if service.Spec.Type == corev1.ServiceTypeExternalName {
+				qr.hit = true
+				qr.selection = Selection{Service: service}
+				break
+			}
+
+			if pod, found := m.selectedPod(service); found {
+				qr.hit = true
+				qr.selection = Selection{Service: service, Pod: pod, Endpoints: m.statefulSetEndpoints(service)}
+				break
+			}
+		}
+
+		result = append(result, qr)
+	}
+	return result
+}
+
+// selectedPod returns the first Pod selected by a Service's selector labels in the Service's namespace.
+func (m *Manifests) selectedPod(svc corev1.Service) (corev1.Pod, bool) {
+	if len(svc.Spec.Selector) == 0 {
+		return corev1.Pod{}, false
+	}
+
+	selector := labels.SelectorFromSet(svc.Spec.Selector)
+	for _, pod := range m.Pods {
+		if sameNamespace(pod.Namespace, svc.Namespace) && selector.Matches(labels.Set(pod.Labels)) {
+			return pod, true
+		}
+	}
+	return corev1.Pod{}, false
+}
+
+// statefulSetEndpoints returns the expected endpoints of StatefulSet Pods governed by a headless Service.
+// Endpoints use the StatefulSet's ordinal Pod hostnames, e.g. web-0, web-1.
+func (m *Manifests) statefulSetEndpoints(svc corev1.Service) []discoveryv1.Endpoint {
+	var out []discoveryv1.Endpoint
+	for _, sts := range m.StatefulSets {
+		if !sameNamespace(sts.Namespace, svc.Namespace) || sts.Spec.ServiceName != svc.Name {
+			continue
+		}
+
+		replicas := int32(1)
+		if sts.Spec.Replicas != nil {
+			replicas = *sts.Spec.Replicas
+		}
+
+		for i := int32(0); i < replicas; i++ {
+			hostname := fmt.Sprintf("%s-%d", sts.Name, i)
+			out = append(out, discoveryv1.Endpoint{Hostname: &hostname})
+		}
+	}
+	return out
+}
+
+// sameNamespace returns whether manifest objects share a namespace.
+// Objects without a namespace are applied to any namespace, so share every namespace.
+func sameNamespace(a, b string) bool {
+	return a == b || len(a) == 0 || len(b) == 0
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestManifestsReadList(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		services int
+		wantErr  bool
+	}{
+		{
+			name: "services",
+			manifest: `
+kind: List
+items:
+  - kind: Service
+    metadata: {name: a}
+  - kind: Service
+    metadata: {name: b}
+`,
+			services: 2,
+		},
+		{
+			name:     "null item",
+			manifest: "kind: List\nitems:\n  - null\n",
+			wantErr:  true,
+		},
+		{
+			name:     "scalar item",
+			manifest: "kind: List\nitems:\n  - Service\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Manifests
+			err := m.Read(strings.NewReader(tt.manifest))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(m.Services) != tt.services {
+				t.Errorf("read %d Services, want %d", len(m.Services), tt.services)
+			}
+		})
+	}
+}
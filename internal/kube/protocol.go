@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Protocol an application protocol served by a K8s Service port.
+type Protocol string
+
+const (
+	ProtocolHTTP     Protocol = "http"
+	ProtocolHTTPS    Protocol = "https"
+	ProtocolH2C      Protocol = "h2c"
+	ProtocolWS       Protocol = "ws"
+	ProtocolWSS      Protocol = "wss"
+	ProtocolSocketIO Protocol = "socketio"
+	ProtocolGRPC     Protocol = "grpc"
+)
+
+// namedProtocols protocols recognised by port naming conventions, e.g. grpc-api or ws.
+var namedProtocols = []Protocol{ProtocolHTTPS, ProtocolH2C, ProtocolWSS, ProtocolWS, ProtocolSocketIO, ProtocolGRPC, ProtocolHTTP}
+
+// PortProtocol returns the application protocol served by a K8s Service port.
+// The protocol is taken from the port's appProtocol, or else port naming conventions
+// like grpc-, ws- or h2c- prefixes. Port 443 defaults to HTTPS, all other ports default to HTTP.
+func PortProtocol(port corev1.ServicePort) Protocol {
+	if port.AppProtocol != nil {
+		appProtocol := strings.TrimPrefix(strings.ToLower(*port.AppProtocol), "kubernetes.io/")
+		if protocol, ok := parseProtocol(appProtocol); ok {
+			return protocol
+		}
+	}
+
+	name := strings.ToLower(port.Name)
+	for _, protocol := range namedProtocols {
+		if name == string(protocol) || strings.HasPrefix(name, string(protocol)+"-") {
+			return protocol
+		}
+	}
+
+	if port.Port == 443 {
+		return ProtocolHTTPS
+	}
+	return ProtocolHTTP
+}
+
+// parseProtocol parses a known application protocol.
+func parseProtocol(value string) (Protocol, bool) {
+	switch value {
+	case "http", "http2":
+		return ProtocolHTTP, true
+	case "https":
+		return ProtocolHTTPS, true
+	case "h2c":
+		return ProtocolH2C, true
+	case "ws", "websocket":
+		return ProtocolWS, true
+	case "wss":
+		return ProtocolWSS, true
+	case "socketio", "socket.io":
+		return ProtocolSocketIO, true
+	case "grpc", "grpc-web":
+		return ProtocolGRPC, true
+	}
+	return "", false
+}
+
+// IsStreaming returns whether a protocol is tested using a non-HTTP Artillery engine.
+func (p Protocol) IsStreaming() bool {
+	switch p {
+	case ProtocolWS, ProtocolWSS, ProtocolSocketIO, ProtocolGRPC:
+		return true
+	}
+	return false
+}
+
+// Scheme returns the url scheme used to target a protocol.
+func (p Protocol) Scheme() string {
+	switch p {
+	case ProtocolHTTPS:
+		return "https"
+	case ProtocolWS, ProtocolWSS:
+		return string(p)
+	}
+	return "http"
+}
+
+// ServicePorts a list of K8s Service ports.
+type ServicePorts []ServicePort
+
+// ServicePort a K8s Service port serving an application protocol.
+type ServicePort struct {
+	Name     string
+	Url      *url.URL
+	Protocol Protocol
+}
+
+// StreamingPorts returns a query result's K8s Service ports tested using non-HTTP Artillery engines.
+// See Selection.StreamingPorts.
+func (qr QueryResult) StreamingPorts() ServicePorts {
+	return qr.selection.StreamingPorts()
+}
+
+// StreamingPorts returns the Service ports serving WebSocket, Socket.io or gRPC protocols.
+// Headless Services are targeted using a port's target port, named target ports resolved against the Pod's container ports.
+func (s Selection) StreamingPorts() ServicePorts {
+	var out ServicePorts
+	if s.IsExternalName() {
+		return out
+	}
+
+	for _, servicePort := range s.Service.Spec.Ports {
+		protocol := PortProtocol(servicePort)
+		if !protocol.IsStreaming() {
+			continue
+		}
+
+		port := servicePort.Port
+		if targetPort := s.podPort(servicePort.TargetPort); s.IsHeadless() && targetPort > 0 {
+			port = targetPort
+		}
+
+		name := servicePort.Name
+		if len(name) == 0 {
+			name = fmt.Sprintf("%d", servicePort.Port)
+		}
+
+		out = append(out, ServicePort{
+			Name: name,
+			Url: &url.URL{
+				Scheme: protocol.Scheme(),
+				Host:   fmt.Sprintf("%s:%d", s.serviceName(), port),
+			},
+			Protocol: protocol,
+		})
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestStreamingPorts(t *testing.T) {
+	tests := []struct {
+		name      string
+		clusterIP string
+		port      corev1.ServicePort
+		want      string
+	}{
+		{
+			name: "cluster ip",
+			port: corev1.ServicePort{Name: "grpc", Port: 9090, TargetPort: intstr.FromString("grpc")},
+			want: "orders:9090",
+		},
+		{
+			name:      "headless numbered target port",
+			clusterIP: corev1.ClusterIPNone,
+			port:      corev1.ServicePort{Name: "grpc", Port: 9090, TargetPort: intstr.FromInt(50051)},
+			want:      "orders:50051",
+		},
+		{
+			name:      "headless named target port",
+			clusterIP: corev1.ClusterIPNone,
+			port:      corev1.ServicePort{Name: "grpc", Port: 9090, TargetPort: intstr.FromString("grpc")},
+			want:      "orders:50051",
+		},
+		{
+			name:      "headless unknown named target port",
+			clusterIP: corev1.ClusterIPNone,
+			port:      corev1.ServicePort{Name: "grpc", Port: 9090, TargetPort: intstr.FromString("rpc")},
+			want:      "orders:9090",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection := Selection{
+				Service: corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "orders"},
+					Spec:       corev1.ServiceSpec{ClusterIP: tt.clusterIP, Ports: []corev1.ServicePort{tt.port}},
+				},
+				Pod: corev1.Pod{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{Ports: []corev1.ContainerPort{{Name: "grpc", ContainerPort: 50051}}},
+						},
+					},
+				},
+			}
+
+			ports := selection.StreamingPorts()
+			if len(ports) != 1 {
+				t.Fatalf("got %d streaming ports, want 1", len(ports))
+			}
+			if ports[0].Url.Host != tt.want {
+				t.Errorf("streaming port host = %q, want %q", ports[0].Url.Host, tt.want)
+			}
+		})
+	}
+}
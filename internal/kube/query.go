@@ -15,13 +15,19 @@ package kube
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// ExternalPathsAnnotation lists the comma separated paths to test for an ExternalName Service.
+// e.g. artillery.io/external-paths: "/health, /v1/status"
+const ExternalPathsAnnotation = "artillery.io/external-paths"
+
 // DoQuery queries a K8s cluster for K8s services using specified service names and namespace.
 // It returns a list of query results, one for each found and missed service name.
 func DoQuery(ctx context.Context, svcNames []string, ns string, ctl *Client) (QueryResults, error) {
@@ -37,6 +43,14 @@ func DoQuery(ctx context.Context, svcNames []string, ns string, ctl *Client) (Qu
 		}
 
 		if strings.ToLower(service.Name) == strings.ToLower(svcName) {
+			// ExternalName Services have no selected Pods, they alias an external DNS name.
+			if service.Spec.Type == corev1.ServiceTypeExternalName {
+				qr.hit = true
+				qr.selection = Selection{Service: *service}
+				result = append(result, qr)
+				continue
+			}
+
 			pods, err := ctl.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{
 				LabelSelector: selectorLabels(service),
 			})
@@ -45,8 +59,13 @@ func DoQuery(ctx context.Context, svcNames []string, ns string, ctl *Client) (Qu
 			}
 
 			if len(pods.Items) > 0 {
+				endpoints, err := serviceEndpoints(ctx, service, ns, ctl)
+				if err != nil {
+					return nil, err
+				}
+
 				qr.hit = true
-				qr.selection = Selection{Service: *service, Pod: pods.Items[0]}
+				qr.selection = Selection{Service: *service, Pod: pods.Items[0], Endpoints: endpoints}
 			}
 		}
 
@@ -55,6 +74,22 @@ func DoQuery(ctx context.Context, svcNames []string, ns string, ctl *Client) (Qu
 	return result, nil
 }
 
+// serviceEndpoints returns all endpoints listed in a K8s Service's EndpointSlices.
+func serviceEndpoints(ctx context.Context, svc *corev1.Service, ns string, ctl *Client) ([]discoveryv1.Endpoint, error) {
+	slices, err := ctl.DiscoveryV1().EndpointSlices(ns).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", discoveryv1.LabelServiceName, svc.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []discoveryv1.Endpoint
+	for _, slice := range slices.Items {
+		out = append(out, slice.Endpoints...)
+	}
+	return out, nil
+}
+
 // QueryResults defines a list query results.
 type QueryResults []QueryResult
 
@@ -83,7 +118,7 @@ func (r QueryResults) HasLivenessHits() bool {
 func (r QueryResults) LivenessMisses() QueryResults {
 	var out QueryResults
 	for _, queryResult := range r {
-		if queryResult.QueryHit() && !queryResult.LivenessHit() {
+		if queryResult.QueryHit() && !queryResult.LivenessHit() && !queryResult.IsExternalName() {
 			out = append(out, queryResult)
 		}
 	}
@@ -101,6 +136,53 @@ func (r QueryResults) LivenessHits() QueryResults {
 	return out
 }
 
+// NoReadyEndpoints returns any K8s Services that DO EXIST BUT have no ready endpoints to receive traffic.
+func (r QueryResults) NoReadyEndpoints() QueryResults {
+	var out QueryResults
+	for _, queryResult := range r {
+		if queryResult.QueryHit() && !queryResult.IsExternalName() && len(queryResult.selection.ReadyEndpoints()) == 0 {
+			out = append(out, queryResult)
+		}
+	}
+	return out
+}
+
+// EndpointHits returns any K8s Services that DO EXIST AND can expose any HTTP Get liveness probes,
+// declare test endpoints using annotations, or expose streaming ports, e.g. WebSocket.
+func (r QueryResults) EndpointHits() QueryResults {
+	var out QueryResults
+	for _, queryResult := range r {
+		if queryResult.QueryHit() && !queryResult.IsExternalName() &&
+			(queryResult.LivenessHit() || queryResult.HasAnnotatedEndpoints() || len(queryResult.StreamingPorts()) > 0) {
+			out = append(out, queryResult)
+		}
+	}
+	return out
+}
+
+// EndpointMisses return any K8s Services do exist BUT CANNOT expose any HTTP Get liveness probes,
+// declare no test endpoints using annotations, and expose no streaming ports.
+func (r QueryResults) EndpointMisses() QueryResults {
+	var out QueryResults
+	for _, queryResult := range r.LivenessMisses() {
+		if !queryResult.HasAnnotatedEndpoints() && len(queryResult.StreamingPorts()) == 0 {
+			out = append(out, queryResult)
+		}
+	}
+	return out
+}
+
+// ExternalNameHits returns any K8s Services that DO EXIST AND are ExternalName Services.
+func (r QueryResults) ExternalNameHits() QueryResults {
+	var out QueryResults
+	for _, queryResult := range r {
+		if queryResult.QueryHit() && queryResult.IsExternalName() {
+			out = append(out, queryResult)
+		}
+	}
+	return out
+}
+
 // QueryResult defines the result of a K8s Services query.
 type QueryResult struct {
 	serviceName string
@@ -124,6 +206,35 @@ func (qr QueryResult) ServiceProbes() ServiceProbes {
 	return qr.selection.ServiceProbes()
 }
 
+// PodServiceProbes returns a K8s Service's exposed HTTP Get liveness Probes for a query result
+// targeting every ready Pod individually using per-Pod DNS names.
+// Only headless Services provide per-Pod DNS names.
+func (qr QueryResult) PodServiceProbes() ServiceProbes {
+	return qr.selection.PodServiceProbes()
+}
+
+// IsHeadless returns whether a query result found a headless K8s Service.
+func (qr QueryResult) IsHeadless() bool {
+	return qr.selection.IsHeadless()
+}
+
+// IsExternalName returns whether a query result found an ExternalName K8s Service.
+func (qr QueryResult) IsExternalName() bool {
+	return qr.selection.IsExternalName()
+}
+
+// ExternalNameProbes returns HTTP Get endpoints targeting an ExternalName K8s Service's external name.
+// See Selection.ExternalNameProbes.
+func (qr QueryResult) ExternalNameProbes(paths []string) ServiceProbes {
+	return qr.selection.ExternalNameProbes(paths)
+}
+
+// ServiceUrl returns the url of a query result's K8s Service first port.
+// See Selection.ServiceUrl.
+func (qr QueryResult) ServiceUrl() *url.URL {
+	return qr.selection.ServiceUrl()
+}
+
 // QueriedServiceName returns a query result's queried service name.
 func (qr QueryResult) QueriedServiceName() string {
 	return qr.serviceName
@@ -137,15 +248,67 @@ func (qr QueryResult) SelectionServiceName() string {
 type ServiceProbes []ServiceProbe
 
 // ServiceProbe is a list of HTTP Get liveness probes for a K8s Service.
+// Protocol is the application protocol served by the Service port exposing the probes.
 type ServiceProbe struct {
 	Url      *url.URL
 	HTTPGets []*corev1.HTTPGetAction
+	Protocol Protocol
 }
 
 // Selection a selection pairs a K8s Service and a Pod based on a Service's selector labels.
+// Endpoints are the Service's EndpointSlice endpoints used to route traffic to all selected Pods.
 type Selection struct {
-	Service corev1.Service
-	Pod     corev1.Pod
+	Service   corev1.Service
+	Pod       corev1.Pod
+	Endpoints []discoveryv1.Endpoint
+}
+
+// IsHeadless returns whether the K8s Service in a service + pod selection is headless.
+// Headless Services resolve directly to Pod IPs, bypassing any port mapping.
+func (s Selection) IsHeadless() bool {
+	return s.Service.Spec.ClusterIP == corev1.ClusterIPNone
+}
+
+// IsExternalName returns whether the K8s Service in a service + pod selection is an ExternalName Service.
+func (s Selection) IsExternalName() bool {
+	return s.Service.Spec.Type == corev1.ServiceTypeExternalName
+}
+
+// ServiceUrl returns the url of a Service's first port, or nil when the Service has no ports.
+// Headless Services are targeted using the first port's target port.
+func (s Selection) ServiceUrl() *url.URL {
+	if len(s.Service.Spec.Ports) == 0 {
+		return nil
+	}
+
+	servicePort := s.Service.Spec.Ports[0]
+	port := servicePort.Port
+	if targetPort := s.podPort(servicePort.TargetPort); s.IsHeadless() && targetPort > 0 {
+		port = targetPort
+	}
+
+	scheme := "http"
+	if PortProtocol(servicePort) == ProtocolHTTPS {
+		scheme = "https"
+	}
+
+	return &url.URL{
+		Scheme: scheme,
+		Host:   fmt.Sprintf("%s:%d", s.serviceName(), port),
+	}
+}
+
+// ReadyEndpoints returns the Service endpoints that are ready to receive traffic.
+// An unknown ready condition is interpreted as ready, as per the EndpointSlice API.
+func (s Selection) ReadyEndpoints() []discoveryv1.Endpoint {
+	var out []discoveryv1.Endpoint
+	for _, endpoint := range s.Endpoints {
+		if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+			continue
+		}
+		out = append(out, endpoint)
+	}
+	return out
 }
 
 // serviceName the name of the K8s Service in service + pod selection.
@@ -170,7 +333,7 @@ func (s Selection) ServiceProbes() ServiceProbes {
 
 	for _, servicePort := range s.Service.Spec.Ports {
 		var livenessCollector []*corev1.HTTPGetAction
-		svcTargetPort := servicePort.TargetPort.IntVal
+		svcTargetPort := s.podPort(servicePort.TargetPort)
 
 		for _, cntnr := range s.Pod.Spec.Containers {
 			if cntnr.LivenessProbe == nil {
@@ -178,7 +341,7 @@ func (s Selection) ServiceProbes() ServiceProbes {
 			}
 
 			httpGet := cntnr.LivenessProbe.HTTPGet
-			if httpGet == nil || svcTargetPort != httpGet.Port.IntVal {
+			if httpGet == nil || svcTargetPort == 0 || svcTargetPort != containerPort(cntnr, httpGet.Port) {
 				continue
 			}
 
@@ -186,12 +349,28 @@ func (s Selection) ServiceProbes() ServiceProbes {
 		}
 
 		if len(livenessCollector) > 0 {
+			port := servicePort.Port
+			if s.IsHeadless() {
+				port = svcTargetPort
+			}
+
+			protocol := PortProtocol(servicePort)
+			if livenessCollector[0].Scheme == corev1.URISchemeHTTPS {
+				protocol = ProtocolHTTPS
+			}
+
+			scheme := "http"
+			if protocol == ProtocolHTTPS {
+				scheme = "https"
+			}
+
 			probe := ServiceProbe{
 				Url: &url.URL{
-					Scheme: "http",
-					Host:   fmt.Sprintf("%s:%d", s.serviceName(), servicePort.Port),
+					Scheme: scheme,
+					Host:   fmt.Sprintf("%s:%d", s.serviceName(), port),
 				},
 				HTTPGets: livenessCollector,
+				Protocol: protocol,
 			}
 			out = append(out, probe)
 		}
@@ -200,6 +379,106 @@ func (s Selection) ServiceProbes() ServiceProbes {
 	return out
 }
 
+// ExternalNameProbes returns HTTP Get endpoints targeting an ExternalName Service's spec.externalName,
+// one for each of the Service's ports.
+// Endpoint paths are taken from the supplied paths, or else the ExternalPathsAnnotation.
+// Defaults to the root path to test connectivity.
+func (s Selection) ExternalNameProbes(paths []string) ServiceProbes {
+	var out ServiceProbes
+	if !s.IsExternalName() || len(s.Service.Spec.ExternalName) == 0 {
+		return out
+	}
+
+	if len(paths) == 0 {
+		paths = annotationList(s.Service.Annotations[ExternalPathsAnnotation])
+	}
+
+	if len(paths) == 0 {
+		paths = []string{"/"}
+	}
+
+	var gets []*corev1.HTTPGetAction
+	for _, path := range paths {
+		gets = append(gets, &corev1.HTTPGetAction{Path: path})
+	}
+
+	if len(s.Service.Spec.Ports) == 0 {
+		return append(out, ServiceProbe{
+			Url:      &url.URL{Scheme: "http", Host: s.Service.Spec.ExternalName},
+			HTTPGets: gets,
+		})
+	}
+
+	for _, servicePort := range s.Service.Spec.Ports {
+		protocol := PortProtocol(servicePort)
+		if protocol.IsStreaming() {
+			continue
+		}
+
+		out = append(out, ServiceProbe{
+			Url: &url.URL{
+				Scheme: protocol.Scheme(),
+				Host:   fmt.Sprintf("%s:%d", s.Service.Spec.ExternalName, servicePort.Port),
+			},
+			HTTPGets: gets,
+			Protocol: protocol,
+		})
+	}
+
+	return out
+}
+
+// PodServiceProbes returns Pod HTTP Get liveness probes that a headless Service can expose,
+// one for every ready Pod using the Pod's DNS name, e.g. a StatefulSet's ordinal hostname.
+// Ready Pods without a hostname are targeted using their IP address.
+func (s Selection) PodServiceProbes() ServiceProbes {
+	var out ServiceProbes
+	if !s.IsHeadless() {
+		return out
+	}
+
+	probes := s.ServiceProbes()
+	for _, endpoint := range s.ReadyEndpoints() {
+		host := s.podHost(endpoint)
+		if len(host) == 0 {
+			continue
+		}
+
+		for _, probe := range probes {
+			podUrl := *probe.Url
+			podUrl.Host = net.JoinHostPort(host, probe.Url.Port())
+			out = append(out, ServiceProbe{Url: &podUrl, HTTPGets: probe.HTTPGets, Protocol: probe.Protocol})
+		}
+	}
+
+	return out
+}
+
+// podHost returns the per-Pod DNS name of a headless Service endpoint.
+// e.g. <hostname>.<service-name> for a StatefulSet Pod.
+func (s Selection) podHost(endpoint discoveryv1.Endpoint) string {
+	if endpoint.Hostname != nil && len(*endpoint.Hostname) > 0 {
+		return fmt.Sprintf("%s.%s", *endpoint.Hostname, s.serviceName())
+	}
+
+	if len(endpoint.Addresses) > 0 {
+		return endpoint.Addresses[0]
+	}
+
+	return ""
+}
+
+// annotationList returns the trimmed non-empty values of a comma separated annotation value.
+func annotationList(value string) []string {
+	var out []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 // selectorLabels returns a K8s Service's selector labels.
 // selector labels provide labels to identify a service's downstream Pods.
 func selectorLabels(svc *corev1.Service) string {
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestPodServiceProbes(t *testing.T) {
+	hostname := "web-0"
+	selection := Selection{
+		Service: corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "nginx"},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: corev1.ClusterIPNone,
+				Selector:  map[string]string{"app": "nginx"},
+				Ports:     []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+			},
+		},
+		Pod: corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						LivenessProbe: &corev1.Probe{
+							Handler: corev1.Handler{
+								HTTPGet: &corev1.HTTPGetAction{Path: "/", Port: intstr.FromInt(8080)},
+							},
+						},
+					},
+				},
+			},
+		},
+		Endpoints: []discoveryv1.Endpoint{
+			{Hostname: &hostname, Addresses: []string{"10.0.0.1"}},
+			{Addresses: []string{"10.0.0.2"}},
+			{Addresses: []string{"fd00::3"}},
+		},
+	}
+
+	want := []string{"web-0.nginx:8080", "10.0.0.2:8080", "[fd00::3]:8080"}
+	probes := selection.PodServiceProbes()
+	if len(probes) != len(want) {
+		t.Fatalf("got %d probes, want %d", len(probes), len(want))
+	}
+	for i, probe := range probes {
+		if probe.Url.Host != want[i] {
+			t.Errorf("probe %d host = %q, want %q", i, probe.Url.Host, want[i])
+		}
+	}
+}
+
+func TestExternalNameProbes(t *testing.T) {
+	tests := []struct {
+		name  string
+		ports []corev1.ServicePort
+		want  []string
+	}{
+		{name: "no ports", want: []string{"api.example.com"}},
+		{
+			name:  "http and streaming ports",
+			ports: []corev1.ServicePort{{Name: "http", Port: 80}, {Name: "grpc", Port: 9090}},
+			want:  []string{"api.example.com:80"},
+		},
+		{
+			name:  "streaming ports only",
+			ports: []corev1.ServicePort{{Name: "grpc", Port: 9090}, {Name: "ws", Port: 8080}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection := Selection{
+				Service: corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+					Spec: corev1.ServiceSpec{
+						Type:         corev1.ServiceTypeExternalName,
+						ExternalName: "api.example.com",
+						Ports:        tt.ports,
+					},
+				},
+			}
+
+			var hosts []string
+			for _, probe := range selection.ExternalNameProbes(nil) {
+				hosts = append(hosts, probe.Url.Host)
+			}
+			if len(hosts) != len(tt.want) {
+				t.Fatalf("probe hosts = %v, want %v", hosts, tt.want)
+			}
+			for i := range hosts {
+				if hosts[i] != tt.want[i] {
+					t.Errorf("probe %d host = %q, want %q", i, hosts[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// GetService queries a K8s cluster for a Service using a specified name and namespace.
+// It returns nil when the Service cannot be found.
+func GetService(ctx context.Context, name, ns string, ctl *Client) (*corev1.Service, error) {
+	svc, err := ctl.CoreV1().Services(ns).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return svc, nil
+}
+
+// ClusterServiceHost returns the Service name and namespace an in-cluster hostname resolves to,
+// e.g. orders, orders.shop, orders.shop.svc, orders.shop.svc.cluster.local or a per-Pod name orders-0.orders.shop.svc.
+// Two label hostnames are only in-cluster for the supplied namespace, other hostnames are external.
+// Names and namespaces that are not DNS-1123 labels cannot be Services, e.g. paths.
+func ClusterServiceHost(host, ns string) (string, string, bool) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if len(host) == 0 || host == "localhost" || net.ParseIP(host) != nil {
+		return "", "", false
+	}
+
+	name, namespace, ok := "", "", false
+	labels := strings.Split(host, ".")
+	for i := 2; i < len(labels) && !ok; i++ {
+		if labels[i] == "svc" {
+			name, namespace, ok = labels[i-2], labels[i-1], true
+		}
+	}
+
+	switch {
+	case ok:
+	case len(labels) == 1:
+		name, namespace, ok = labels[0], ns, true
+	case len(labels) == 2 && labels[1] == ns:
+		name, namespace, ok = labels[0], labels[1], true
+	}
+
+	if !ok || len(validation.IsDNS1123Label(name)) > 0 || len(validation.IsDNS1123Label(namespace)) > 0 {
+		return "", "", false
+	}
+	return name, namespace, true
+}
@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import "testing"
+
+func TestClusterServiceHost(t *testing.T) {
+	tests := []struct {
+		host   string
+		name   string
+		ns     string
+		wantOk bool
+	}{
+		{host: "orders", name: "orders", ns: "shop", wantOk: true},
+		{host: "orders.shop", name: "orders", ns: "shop", wantOk: true},
+		{host: "orders.shop.svc.cluster.local.", name: "orders", ns: "shop", wantOk: true},
+		{host: "orders-0.orders.shop.svc", name: "orders", ns: "shop", wantOk: true},
+		{host: "orders.billing.svc", name: "orders", ns: "billing", wantOk: true},
+		{host: "orders.billing"},
+		{host: "api.example.com"},
+		{host: "localhost"},
+		{host: "10.0.0.1"},
+		{host: "/orders"},
+		{host: "orders?page=1"},
+		{host: "orders_v2"},
+		{host: ""},
+	}
+
+	for _, tt := range tests {
+		name, ns, ok := ClusterServiceHost(tt.host, "shop")
+		if ok != tt.wantOk || name != tt.name || ns != tt.ns {
+			t.Errorf("ClusterServiceHost(%q) = %q, %q, %v, want %q, %q, %v", tt.host, name, ns, ok, tt.name, tt.ns, tt.wantOk)
+		}
+	}
+}
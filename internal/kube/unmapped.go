@@ -0,0 +1,218 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// unmappedPort a Pod port exposing HTTP Get liveness probes that a Service does not map.
+type unmappedPort struct {
+	port     int32
+	httpGets []*corev1.HTTPGetAction
+}
+
+// HasUnmappedProbes returns whether a query result found a K8s Service selecting Pods with HTTP Get
+// liveness probes on ports the Service does not map.
+func (qr QueryResult) HasUnmappedProbes() bool {
+	return len(qr.selection.unmappedPorts()) > 0
+}
+
+// UnmappedPodProbes returns HTTP Get liveness probes on ports a query result's K8s Service does not map.
+// See Selection.UnmappedPodProbes.
+func (qr QueryResult) UnmappedPodProbes() ServiceProbes {
+	return qr.selection.UnmappedPodProbes()
+}
+
+// CompanionServiceProbes returns HTTP Get liveness probes on ports a query result's K8s Service does not map.
+// See Selection.CompanionServiceProbes.
+func (qr QueryResult) CompanionServiceProbes() ServiceProbes {
+	return qr.selection.CompanionServiceProbes()
+}
+
+// CompanionService returns a headless Service exposing the ports a query result's K8s Service does not map.
+// See Selection.CompanionService.
+func (qr QueryResult) CompanionService() *corev1.Service {
+	return qr.selection.CompanionService()
+}
+
+// UnmappedProbeHits returns any K8s Services that DO EXIST BUT CANNOT expose any HTTP Get liveness probes,
+// while selecting Pods with HTTP Get liveness probes on ports the Service does not map.
+func (r QueryResults) UnmappedProbeHits() QueryResults {
+	var out QueryResults
+	for _, queryResult := range r.EndpointMisses() {
+		if queryResult.HasUnmappedProbes() {
+			out = append(out, queryResult)
+		}
+	}
+	return out
+}
+
+// UnmappedPodProbes returns HTTP Get liveness probes on ports the Service does not map,
+// targeting every ready Pod directly using its IP address, or per-Pod DNS name for headless Services.
+func (s Selection) UnmappedPodProbes() ServiceProbes {
+	var out ServiceProbes
+	ports := s.unmappedPorts()
+	for _, endpoint := range s.ReadyEndpoints() {
+		host := ""
+		if s.IsHeadless() {
+			host = s.podHost(endpoint)
+		} else if len(endpoint.Addresses) > 0 {
+			host = endpoint.Addresses[0]
+		}
+
+		if len(host) == 0 {
+			continue
+		}
+
+		for _, p := range ports {
+			out = append(out, ServiceProbe{
+				Url: &url.URL{
+					Scheme: "http",
+					Host:   net.JoinHostPort(host, strconv.Itoa(int(p.port))),
+				},
+				HTTPGets: p.httpGets,
+				Protocol: ProtocolHTTP,
+			})
+		}
+	}
+	return out
+}
+
+// CompanionServiceProbes returns HTTP Get liveness probes on ports the Service does not map,
+// targeting the Service's companion headless Service. See Selection.CompanionService.
+func (s Selection) CompanionServiceProbes() ServiceProbes {
+	var out ServiceProbes
+	for _, p := range s.unmappedPorts() {
+		out = append(out, ServiceProbe{
+			Url: &url.URL{
+				Scheme: "http",
+				Host:   fmt.Sprintf("%s:%d", s.companionServiceName(), p.port),
+			},
+			HTTPGets: p.httpGets,
+			Protocol: ProtocolHTTP,
+		})
+	}
+	return out
+}
+
+// CompanionService returns a headless Service selecting the same Pods as the Service,
+// exposing the ports with HTTP Get liveness probes the Service does not map, e.g. admin and health ports.
+// It returns nil when the Service maps all probe ports.
+func (s Selection) CompanionService() *corev1.Service {
+	ports := s.unmappedPorts()
+	if len(ports) == 0 {
+		return nil
+	}
+
+	var servicePorts []corev1.ServicePort
+	for _, p := range ports {
+		servicePorts = append(servicePorts, corev1.ServicePort{
+			Name:       fmt.Sprintf("probe-%d", p.port),
+			Port:       p.port,
+			TargetPort: intstr.FromInt(int(p.port)),
+		})
+	}
+
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.companionServiceName(),
+			Namespace: s.Service.Namespace,
+			Labels: map[string]string{
+				"artillery.io/component": "probes-service",
+				"artillery.io/service":   s.serviceName(),
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  s.Service.Spec.Selector,
+			Ports:     servicePorts,
+		},
+	}
+}
+
+// companionServiceName the name of the Service's companion headless Service.
+func (s Selection) companionServiceName() string {
+	return fmt.Sprintf("%s-probes", s.serviceName())
+}
+
+// unmappedPorts returns the selected Pod's ports exposing HTTP Get liveness probes that the Service does not map.
+// Named probe ports are resolved using container ports.
+func (s Selection) unmappedPorts() []unmappedPort {
+	var out []unmappedPort
+	if len(s.Service.Spec.Selector) == 0 || s.IsExternalName() {
+		return out
+	}
+
+	mapped := map[int32]bool{}
+	for _, servicePort := range s.Service.Spec.Ports {
+		mapped[s.podPort(servicePort.TargetPort)] = true
+	}
+
+	index := map[int32]int{}
+	for _, cntnr := range s.Pod.Spec.Containers {
+		if cntnr.LivenessProbe == nil || cntnr.LivenessProbe.HTTPGet == nil {
+			continue
+		}
+
+		httpGet := cntnr.LivenessProbe.HTTPGet
+		port := containerPort(cntnr, httpGet.Port)
+		if port == 0 || mapped[port] {
+			continue
+		}
+
+		if i, found := index[port]; found {
+			out[i].httpGets = append(out[i].httpGets, httpGet)
+			continue
+		}
+
+		index[port] = len(out)
+		out = append(out, unmappedPort{port: port, httpGets: []*corev1.HTTPGetAction{httpGet}})
+	}
+	return out
+}
+
+// podPort resolves a Service target port number, using the selected Pod's named container ports.
+func (s Selection) podPort(port intstr.IntOrString) int32 {
+	for _, cntnr := range s.Pod.Spec.Containers {
+		if p := containerPort(cntnr, port); p > 0 {
+			return p
+		}
+	}
+	return port.IntVal
+}
+
+// containerPort resolves a probe port number, using the container's named ports.
+func containerPort(cntnr corev1.Container, port intstr.IntOrString) int32 {
+	if port.Type == intstr.Int {
+		return port.IntVal
+	}
+
+	for _, p := range cntnr.Ports {
+		if p.Name == port.StrVal {
+			return p.ContainerPort
+		}
+	}
+	return 0
+}
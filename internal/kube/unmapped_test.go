@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package kube
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// probedSelection returns a Service selection whose Pod has HTTP Get liveness probes on its http and admin ports.
+func probedSelection(targetPort intstr.IntOrString) Selection {
+	probe := func(port intstr.IntOrString) *corev1.Probe {
+		return &corev1.Probe{Handler: corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Path: "/health", Port: port}}}
+	}
+
+	return Selection{
+		Service: corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": "web"},
+				Ports:    []corev1.ServicePort{{Port: 80, TargetPort: targetPort}},
+			},
+		},
+		Pod: corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Ports:         []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+						LivenessProbe: probe(intstr.FromString("http")),
+					},
+					{
+						Ports:         []corev1.ContainerPort{{Name: "admin", ContainerPort: 9090}},
+						LivenessProbe: probe(intstr.FromInt(9090)),
+					},
+				},
+			},
+		},
+		Endpoints: []discoveryv1.Endpoint{{Addresses: []string{"fd00::1"}}},
+	}
+}
+
+func TestUnmappedPorts(t *testing.T) {
+	tests := []struct {
+		name       string
+		targetPort intstr.IntOrString
+	}{
+		{name: "numbered target port", targetPort: intstr.FromInt(8080)},
+		{name: "named target port", targetPort: intstr.FromString("http")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := probedSelection(tt.targetPort)
+
+			if probes := s.ServiceProbes(); len(probes) != 1 || probes[0].Url.Host != "web:80" {
+				t.Errorf("ServiceProbes() = %v, want one probe on web:80", probes)
+			}
+
+			ports := s.unmappedPorts()
+			if len(ports) != 1 || ports[0].port != 9090 {
+				t.Fatalf("unmappedPorts() = %v, want only 9090", ports)
+			}
+
+			probes := s.UnmappedPodProbes()
+			if len(probes) != 1 || probes[0].Url.Host != "[fd00::1]:9090" {
+				t.Errorf("UnmappedPodProbes() = %v, want one probe on [fd00::1]:9090", probes)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// dockerHubHost the registry serving Docker Hub images, e.g. artilleryio/artillery.
+const dockerHubHost = "registry-1.docker.io"
+
+// dockerHubConfigKey the docker config.json auths key holding Docker Hub credentials.
+const dockerHubConfigKey = "https://index.docker.io/v1/"
+
+// manifestMediaTypes the manifest types accepted when resolving a digest, multi-platform indexes first
+// so the digest covers every platform.
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// authParam matches the parameters of a WWW-Authenticate challenge, e.g. realm="https://auth.docker.io/token".
+var authParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Reference an image reference, e.g. artilleryio/artillery:latest or registry.example.com/artillery@sha256:...
+type Reference struct {
+	// Name the image name as written, without tag or digest, e.g. artilleryio/artillery.
+	Name   string
+	Tag    string
+	Digest string
+}
+
+// ParseReference parses an image reference, the tag defaults to latest when there is no tag or digest.
+func ParseReference(image string) (Reference, error) {
+	var ref Reference
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+		if !strings.Contains(ref.Digest, ":") {
+			return Reference{}, fmt.Errorf("invalid image %q, digest must be algorithm:hex, e.g. sha256:...", image)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	if len(name) == 0 || strings.ContainsAny(name, " \t") || name != strings.ToLower(name) {
+		return Reference{}, fmt.Errorf("invalid image %q, use a lowercase name, e.g. artilleryio/artillery:latest", image)
+	}
+	if len(ref.Tag) == 0 && len(ref.Digest) == 0 {
+		ref.Tag = "latest"
+	}
+
+	ref.Name = name
+	return ref, nil
+}
+
+// String returns the image reference, pinned to its digest when known.
+func (r Reference) String() string {
+	if len(r.Digest) > 0 {
+		return r.Name + "@" + r.Digest
+	}
+	return r.Name + ":" + r.Tag
+}
+
+// split returns the registry host and repository of the image, Docker Hub when the name has no registry,
+// e.g. registry-1.docker.io and library/busybox.
+func (r Reference) split() (string, string) {
+	host, repo := dockerHubHost, r.Name
+	if first, rest, found := strings.Cut(r.Name, "/"); found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		host, repo = first, rest
+	}
+	if host == "docker.io" || host == "index.docker.io" {
+		host = dockerHubHost
+	}
+	if host == dockerHubHost && !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+	return host, repo
+}
+
+// Resolver resolves image tags to digests using the Docker Registry HTTP API V2.
+// Credentials are read from the docker config.json auths, credential helpers are not supported.
+type Resolver struct {
+	Client *http.Client
+}
+
+// NewResolver returns a Resolver using the default HTTP client.
+func NewResolver() *Resolver {
+	return &Resolver{Client: http.DefaultClient}
+}
+
+// Resolve returns an image reference pinned to the digest of its tag.
+// References already pinned to a digest are returned as is.
+func (rs *Resolver) Resolve(ctx context.Context, ref Reference) (Reference, error) {
+	if len(ref.Digest) > 0 {
+		return ref, nil
+	}
+
+	host, repo := ref.split()
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repo, ref.Tag)
+
+	res, err := rs.head(ctx, manifestURL, "")
+	if err != nil {
+		return ref, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		auth, err := rs.authorize(ctx, ref, host, repo, res.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return ref, err
+		}
+		if res, err = rs.head(ctx, manifestURL, auth); err != nil {
+			return ref, err
+		}
+	}
+
+	switch {
+	case res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden:
+		return ref, fmt.Errorf("cannot resolve %s, not authorized: add credentials with docker login, credential helpers are not supported", ref)
+	case res.StatusCode == http.StatusNotFound:
+		return ref, fmt.Errorf("cannot resolve %s, image not found", ref)
+	case res.StatusCode != http.StatusOK:
+		return ref, fmt.Errorf("cannot resolve %s, registry %s responded %s", ref, host, res.Status)
+	}
+
+	digest := res.Header.Get("Docker-Content-Digest")
+	if len(digest) == 0 {
+		return ref, fmt.Errorf("cannot resolve %s, registry %s returned no digest", ref, host)
+	}
+	ref.Digest = digest
+	return ref, nil
+}
+
+// head requests an image manifest's headers.
+func (rs *Resolver) head(ctx context.Context, manifestURL, auth string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if len(auth) > 0 {
+		req.Header.Set("Authorization", auth)
+	}
+
+	res, err := rs.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach image registry: %w", err)
+	}
+	_ = res.Body.Close()
+	return res, nil
+}
+
+// authorize returns the Authorization header answering a registry's WWW-Authenticate challenge,
+// a bearer token from the registry's token service, or the basic credentials of the docker config.json.
+func (rs *Resolver) authorize(ctx context.Context, ref Reference, host, repo, challenge string) (string, error) {
+	user, pass := dockerCredentials(host)
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+
+	switch scheme {
+	case "basic":
+		if len(user) == 0 {
+			return "", fmt.Errorf("cannot resolve %s, registry %s requires credentials: add them with docker login", ref, host)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass)), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("cannot resolve %s, unsupported registry authentication %q", ref, challenge)
+	}
+
+	params := map[string]string{}
+	for _, m := range authParam.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	if len(params["realm"]) == 0 {
+		return "", fmt.Errorf("cannot resolve %s, registry %s sent no token realm", ref, host)
+	}
+
+	query := url.Values{}
+	if len(params["service"]) > 0 {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if len(scope) == 0 {
+		scope = fmt.Sprintf("repository:%s:pull", repo)
+	}
+	query.Set("scope", scope)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if len(user) > 0 {
+		req.SetBasicAuth(user, pass)
+	}
+
+	res, err := rs.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("cannot resolve %s, token service responded %s", ref, res.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if len(token.Token) == 0 {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// dockerCredentials returns the user and password of a registry host in the docker config.json auths,
+// or empty strings when there are none.
+func dockerCredentials(host string) (string, string) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if len(dir) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return "", ""
+	}
+
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", ""
+	}
+
+	key := host
+	if host == dockerHubHost {
+		key = dockerHubConfigKey
+	}
+	for k, a := range config.Auths {
+		if k != key && strings.TrimPrefix(strings.TrimPrefix(k, "https://"), "http://") != key {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+		if err != nil {
+			return "", ""
+		}
+		user, pass, found := strings.Cut(string(decoded), ":")
+		if !found {
+			return "", ""
+		}
+		return user, pass
+	}
+	return "", ""
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2022.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0.
+ *
+ * If a copy of the MPL was not distributed with
+ * this file, You can obtain one at
+ *
+ *   http://mozilla.org/MPL/2.0/
+ */
+
+package registry
+
+import "testing"
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:9d1c8a6e2b0f4e5a7c3d1b2a0f9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e"
+	tests := []struct {
+		image   string
+		want    Reference
+		wantErr bool
+	}{
+		{image: "artilleryio/artillery", want: Reference{Name: "artilleryio/artillery", Tag: "latest"}},
+		{image: "artilleryio/artillery:2.0.0", want: Reference{Name: "artilleryio/artillery", Tag: "2.0.0"}},
+		{image: "artilleryio/artillery@" + digest, want: Reference{Name: "artilleryio/artillery", Digest: digest}},
+		{image: "artilleryio/artillery:2.0.0@" + digest, want: Reference{Name: "artilleryio/artillery", Tag: "2.0.0", Digest: digest}},
+		{image: "localhost:5000/artillery", want: Reference{Name: "localhost:5000/artillery", Tag: "latest"}},
+		{image: "registry.example.com:5000/load/artillery:v2", want: Reference{Name: "registry.example.com:5000/load/artillery", Tag: "v2"}},
+		{image: "artilleryio/artillery@9d1c8a6e", wantErr: true},
+		{image: "ArtilleryIO/artillery", wantErr: true},
+		{image: ":latest", wantErr: true},
+		{image: "artillery io", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, err := ParseReference(tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseReference() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferenceString(t *testing.T) {
+	ref := Reference{Name: "artilleryio/artillery", Tag: "latest"}
+	if got := ref.String(); got != "artilleryio/artillery:latest" {
+		t.Errorf("String() = %q", got)
+	}
+
+	ref.Digest = "sha256:abc"
+	if got := ref.String(); got != "artilleryio/artillery@sha256:abc" {
+		t.Errorf("String() pinned = %q", got)
+	}
+}
+
+func TestReferenceSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantHost string
+		wantRepo string
+	}{
+		{name: "busybox", wantHost: dockerHubHost, wantRepo: "library/busybox"},
+		{name: "artilleryio/artillery", wantHost: dockerHubHost, wantRepo: "artilleryio/artillery"},
+		{name: "docker.io/busybox", wantHost: dockerHubHost, wantRepo: "library/busybox"},
+		{name: "index.docker.io/artilleryio/artillery", wantHost: dockerHubHost, wantRepo: "artilleryio/artillery"},
+		{name: "localhost/artillery", wantHost: "localhost", wantRepo: "artillery"},
+		{name: "localhost:5000/artillery", wantHost: "localhost:5000", wantRepo: "artillery"},
+		{name: "ghcr.io/artilleryio/artillery", wantHost: "ghcr.io", wantRepo: "artilleryio/artillery"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, repo := Reference{Name: tt.name}.split()
+			if host != tt.wantHost || repo != tt.wantRepo {
+				t.Errorf("split() = %s, %s, want %s, %s", host, repo, tt.wantHost, tt.wantRepo)
+			}
+		})
+	}
+}
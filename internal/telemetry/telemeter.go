@@ -80,3 +80,34 @@ func TelemeterGenerateManifests(
 		)
 	}
 }
+
+// TelemeterLint enqueues a kubectl-artillery lint command event.
+func TelemeterLint(
+	testScriptPath, namespace string,
+	fix bool,
+	tClient posthog.Client,
+	tConfig Config,
+	logger logr.Logger,
+) {
+	if err := enqueue(
+		tClient,
+		tConfig,
+		event{
+			Name: "kubectl-artillery lint",
+			Properties: map[string]interface{}{
+				"source":     "kubectl-artillery-plugin",
+				"testScript": hashEncode(testScriptPath),
+				"namespace":  hashEncode(namespace),
+				"fix":        fix,
+			},
+		},
+		logger,
+	); err != nil {
+		logger.Error(err,
+			"could not broadcast telemetry",
+			"telemetry disable", tConfig.Disable,
+			"telemetry debug", tConfig.Debug,
+			"event", "kubectl-artillery lint",
+		)
+	}
+}